@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"go-upkeep/internal/alert"
 	"go-upkeep/internal/cluster"
 	"go-upkeep/internal/monitor"
 	"go-upkeep/internal/server"
@@ -12,8 +13,11 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/ssh"
@@ -31,9 +35,15 @@ func main() {
 	httpPort := 8080
 	enableStatus := false
 	statusTitle := "System Status"
+	statusGroupBy := ""
+	publicURL := ""
 	clusterMode := "leader"
 	clusterPeer := ""
 	clusterKey  := ""
+	groupWindow := 0
+	maxChecks   := 16
+	secretKey   := ""
+	secretFile  := ""
 
 	if v := os.Getenv("UPKEEP_PORT"); v != "" { if p, err := strconv.Atoi(v); err == nil { portVal = p } }
 	if v := os.Getenv("UPKEEP_DB_TYPE"); v != "" { dbType = v }
@@ -41,10 +51,19 @@ func main() {
 	if v := os.Getenv("UPKEEP_HTTP_PORT"); v != "" { if p, err := strconv.Atoi(v); err == nil { httpPort = p } }
 	if v := os.Getenv("UPKEEP_STATUS_ENABLED"); v == "true" { enableStatus = true }
 	if v := os.Getenv("UPKEEP_STATUS_TITLE"); v != "" { statusTitle = v }
+	if v := os.Getenv("UPKEEP_STATUS_GROUP_BY"); v != "" { statusGroupBy = v }
+	if v := os.Getenv("UPKEEP_PUBLIC_URL"); v != "" { publicURL = v }
 	
 	if v := os.Getenv("UPKEEP_CLUSTER_MODE"); v != "" { clusterMode = v }
 	if v := os.Getenv("UPKEEP_PEER_URL"); v != "" { clusterPeer = v }
 	if v := os.Getenv("UPKEEP_CLUSTER_SECRET"); v != "" { clusterKey = v }
+	if v := os.Getenv("UPKEEP_MAX_CONCURRENCY"); v != "" { if p, err := strconv.Atoi(v); err == nil && p > 0 { maxChecks = p } }
+	if v := os.Getenv("UPKEEP_SECRET_KEY"); v != "" { secretKey = v }
+	if v := os.Getenv("UPKEEP_SECRET_KEY_FILE"); v != "" { secretFile = v }
+	if v := os.Getenv("UPKEEP_RDAP_URL"); v != "" { monitor.RDAPBaseURL = v }
+	if v := os.Getenv("UPKEEP_WHOIS_SERVER"); v != "" { monitor.WHOISServer = v }
+	if v := os.Getenv("UPKEEP_DOCKER_SOCKET"); v != "" { monitor.DockerSocket = v }
+	if v := os.Getenv("UPKEEP_ALERT_GROUP_WINDOW"); v != "" { if p, err := strconv.Atoi(v); err == nil { groupWindow = p } }
 
 	port := flag.Int("port", portVal, "SSH Port")
 	flagDBType := flag.String("db-type", dbType, "Database type")
@@ -60,12 +79,37 @@ func main() {
 		fmt.Printf("Using SQLite: %s\n", *flagDSN)
 	}
 
+	// Nodes sharing a database or a synced backup must seal with the same key; a
+	// file generated on one node can't decrypt credentials written by another
+	if *flagDBType == "postgres" || clusterMode == "follower" || clusterPeer != "" {
+		if secretKey == "" && secretFile == "" {
+			fmt.Println("Secret Key Error: set UPKEEP_SECRET_KEY to the same value on every node (or UPKEEP_SECRET_KEY_FILE=upkeep.key to keep using a key file)")
+			os.Exit(1)
+		}
+		if secretKey == "" { fmt.Printf("WARNING: credentials are sealed with the local key file %s; every node must use a copy of it\n", secretFile) }
+	}
+	if secretFile == "" && *flagDBType != "postgres" {
+		// Keep the generated key beside the database so both live on the same volume
+		dbPath, _, _ := strings.Cut(strings.TrimPrefix(*flagDSN, "file:"), "?")
+		secretFile = filepath.Join(filepath.Dir(dbPath), "upkeep.key")
+	}
+	store.SecretKey = secretKey
+	if secretFile != "" { store.SecretKeyFile = secretFile }
+	if err := store.LoadSecretKey(); err != nil {
+		fmt.Printf("Secret Key Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	store.Log = monitor.AddLog
 	if err := s.Init(); err != nil {
 		fmt.Printf("Database Init Error: %v\n", err)
 		os.Exit(1)
 	}
 	store.SetGlobal(s)
 
+	alert.DefaultGroupWindow = time.Duration(groupWindow) * time.Second
+
+	monitor.MaxConcurrency = maxChecks
 	monitor.StartEngine()
 
 	server.Start(server.ServerConfig{
@@ -73,6 +117,8 @@ func main() {
 		EnableStatus: enableStatus,
 		Title:        statusTitle,
 		ClusterKey:   clusterKey,
+		PublicURL:    publicURL,
+		GroupByTag:   statusGroupBy,
 	})
 
 	cluster.Start(cluster.Config{
@@ -84,8 +130,10 @@ func main() {
 	startSSHServer(*port)
 
 	if isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()) {
-		p := tea.NewProgram(tui.InitialModel(true), tea.WithAltScreen())
+		m := tui.InitialModel(true)
+		p := tea.NewProgram(m, tea.WithAltScreen())
 		if _, err := p.Run(); err != nil { fmt.Printf("Error: %v\n", err) }
+		m.Close()
 	} else {
 		fmt.Println("Go-Upkeep running in HEADLESS mode")
 		done := make(chan os.Signal, 1)
@@ -104,7 +152,9 @@ func startSSHServer(port int) {
 		}),
 		wish.WithMiddleware(
 			bm.Middleware(func(s ssh.Session) (tea.Model, []tea.ProgramOption) {
-				return tui.InitialModel(false), []tea.ProgramOption{tea.WithAltScreen()}
+				m := tui.InitialModel(false)
+				go func() { <-s.Context().Done(); m.Close() }()
+				return m, []tea.ProgramOption{tea.WithAltScreen()}
 			}),
 		),
 	)
@@ -0,0 +1,63 @@
+package alert
+
+import (
+	"fmt"
+	"go-upkeep/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- GROUPING ---
+// Alerts sharing a channel and title that arrive within the grouping window
+// are coalesced into one digest, so a shared outage sends a single message.
+
+// DefaultGroupWindow applies to channels without a "group_window" setting. Zero disables grouping.
+var DefaultGroupWindow time.Duration
+
+type pendingGroup struct {
+	cfg      models.AlertConfig
+	title    string
+	messages []string
+}
+
+var (
+	groups     = make(map[string]*pendingGroup)
+	groupMutex sync.Mutex
+)
+
+// GroupWindow returns the coalescing window for a channel.
+func GroupWindow(cfg models.AlertConfig) time.Duration {
+	if v, ok := cfg.Settings["group_window"]; ok && v != "" {
+		if sec, err := strconv.Atoi(v); err == nil { return time.Duration(sec) * time.Second }
+	}
+	return DefaultGroupWindow
+}
+
+// Dispatch sends an alert through the channel, buffering it first when grouping is enabled.
+func Dispatch(cfg models.AlertConfig, title, message string) {
+	window := GroupWindow(cfg)
+	if window <= 0 { send(cfg, title, message); return }
+
+	key := fmt.Sprintf("%d|%s", cfg.ID, title)
+	groupMutex.Lock(); defer groupMutex.Unlock()
+	if g, ok := groups[key]; ok { g.messages = append(g.messages, message); return }
+	groups[key] = &pendingGroup{cfg: cfg, title: title, messages: []string{message}}
+	time.AfterFunc(window, func() { flushGroup(key) })
+}
+
+func flushGroup(key string) {
+	groupMutex.Lock(); g := groups[key]; delete(groups, key); groupMutex.Unlock()
+	if g == nil { return }
+	if len(g.messages) == 1 { send(g.cfg, g.title, g.messages[0]); return }
+
+	title := fmt.Sprintf("%s (%d monitors)", g.title, len(g.messages))
+	body := fmt.Sprintf("%d monitors changed state within %s:\n• %s", len(g.messages), GroupWindow(g.cfg), strings.Join(g.messages, "\n• "))
+	send(g.cfg, title, body)
+}
+
+func send(cfg models.AlertConfig, title, message string) {
+	provider := GetProvider(cfg)
+	if provider != nil { go func() { provider.Send(title, message) }() }
+}
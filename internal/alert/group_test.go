@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"encoding/json"
+	"go-upkeep/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sent struct{ Title, Message string }
+
+// webhook returns a channel config whose deliveries arrive on the returned channel.
+func webhook(t *testing.T, id int) (models.AlertConfig, chan sent) {
+	got := make(chan sent, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var s sent
+		json.NewDecoder(r.Body).Decode(&s)
+		got <- s
+	}))
+	t.Cleanup(srv.Close)
+	return models.AlertConfig{ID: id, Type: "webhook", Settings: map[string]string{"url": srv.URL}}, got
+}
+
+// collect gathers deliveries until none arrives for quiet.
+func collect(got chan sent, quiet time.Duration) []sent {
+	var out []sent
+	for {
+		select {
+		case s := <-got: out = append(out, s)
+		case <-time.After(quiet): return out
+		}
+	}
+}
+
+func withWindow(t *testing.T, d time.Duration) {
+	prev := DefaultGroupWindow; DefaultGroupWindow = d
+	t.Cleanup(func() { DefaultGroupWindow = prev })
+}
+
+func TestDispatchDigestPerBurst(t *testing.T) {
+	withWindow(t, 100*time.Millisecond)
+	cfg, got := webhook(t, 1)
+	for _, name := range []string{"api", "db", "cache"} { Dispatch(cfg, "🔴 DOWN", "Monitor '"+name+"' is down") }
+	// Recoveries inside the same window still get their own digest
+	for _, name := range []string{"api", "db"} { Dispatch(cfg, "✅ RECOVERY", "Monitor '"+name+"' recovered") }
+
+	out := collect(got, 400*time.Millisecond)
+	if len(out) != 2 { t.Fatalf("got %d deliveries, want one digest per burst: %+v", len(out), out) }
+	byTitle := map[string]string{}
+	for _, s := range out { byTitle[s.Title] = s.Message }
+	down, ok := byTitle["🔴 DOWN (3 monitors)"]
+	if !ok || strings.Count(down, "\n• ") != 3 || !strings.Contains(down, "'cache' is down") { t.Errorf("down digest = %q", down) }
+	rec, ok := byTitle["✅ RECOVERY (2 monitors)"]
+	if !ok || strings.Count(rec, "\n• ") != 2 || strings.Contains(rec, "down") { t.Errorf("recovery digest = %q", rec) }
+}
+
+func TestDispatchSingleAndUngrouped(t *testing.T) {
+	withWindow(t, 50*time.Millisecond)
+	cfg, got := webhook(t, 2)
+	Dispatch(cfg, "🔴 DOWN", "Monitor 'api' is down")
+	if out := collect(got, 200*time.Millisecond); len(out) != 1 || out[0].Title != "🔴 DOWN" || out[0].Message != "Monitor 'api' is down" { t.Errorf("lone alert = %+v, want it sent as is", out) }
+
+	cfg.Settings["group_window"] = "0" // Channel setting overrides the default
+	Dispatch(cfg, "🔴 DOWN", "a"); Dispatch(cfg, "🔴 DOWN", "b")
+	out := collect(got, 200*time.Millisecond)
+	if len(out) != 2 { t.Errorf("ungrouped channel sent %d messages, want 2", len(out)) }
+}
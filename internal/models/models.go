@@ -1,28 +1,250 @@
 package models
 
-import "time"
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Site struct {
 	ID              int
 	Name            string
 	URL             string
-	Type            string // "http" or "push"
+	Type            string // "http", "push", "ping", "postgres", "mysql", "redis", "grpc", "websocket", "transaction", "domain", "ssh", "smtp", "udp", "docker" or "group"
 	Token           string // Secure Token
 	Interval        int
 	AlertID         int
 	CheckSSL        bool
 	ExpiryThreshold int
+	Tags            []string // Free-form labels such as "env:prod", stored in site_tags
+
+	Timeout         int    // sec; 0 = default
+	RedirectPolicy  string // "follow" (default), "none" or "fail"
+	MaxRedirects    int
+	ProxyURL        string // http://, https:// or socks5://
+
+	AuthUser        string // Basic auth when set
+	AuthPass        string // Secret
+	AuthToken       string // Secret; sent as a bearer token
+	ClientCert      string // mTLS certificate: PEM or path to a PEM file
+	ClientKey       string // Secret; PEM or path
 	
+	DSN             string // Secret; database connection string
+	Query           string // Database probe; default SELECT 1 / PING
+	Expect          string // Assertion on the probe result, e.g. "< 30"; banner substring for ssh/smtp
+
+	Service         string // gRPC: service name for health checks; empty = whole server
+	Payload         string // WebSocket: message sent after the handshake; UDP: datagram, text or "hex:..."
+	Steps           []Step // Transaction: HTTP requests run in order
+
+	Members         []GroupMember // Group: monitors whose statuses are combined
+	GroupRule       string        // "all" (default), "any", "atleast" or "weighted"
+	GroupMin        int           // atleast: members that must be up; weighted: % of weight (0 = 50)
+	MuteMembers     bool          // Members send no alerts of their own
+
+	PacketCount     int // Ping: echo requests per check; 0 = 3
+	LossWarn        int // Ping: loss % for DEGRADED; 0 = off
+	LossCrit        int // Ping: loss % for DOWN; 0 = 100
+
+	Cron            string // Push monitors: expected schedule; empty = every Interval
+	Timezone        string // IANA zone for Cron; empty = server local time
+	GracePeriod     int    // sec a heartbeat may be late; 0 = 5s
+	MaxRuntime      int    // sec between start and finish pings; 0 = off
+
 	MaxRetries      int
+	RetryInterval   int // sec; used instead of Interval while a failure is unconfirmed
 	FailureCount    int
+	ParentIDs       []int // Upstream monitors; while any is broken this one is UNREACHABLE
+
+	LatencyWarn     int // ms; DEGRADED after DegradedAfter consecutive slower checks
+	LatencyCrit     int // ms; DEGRADED immediately
+	DegradedAfter   int
+	SlowCount       int
 
 	Status          string
 	StatusCode      int
 	Latency         time.Duration
 	CertExpiry      time.Time
 	HasSSL          bool
+	DomainExpiry    time.Time // Domain monitors: registration expiry from RDAP/WHOIS
+	Registrar       string
+	Banner          string // SSH identification string or SMTP greeting from the last check
+	ContainerState  string // Docker: e.g. "running (healthy)"
+	RestartCount    int    // Docker
+	PacketLoss      float64 // Ping: % of the last check's packets lost
+	RTTMin          time.Duration
+	RTTAvg          time.Duration
+	RTTMax          time.Duration
 	LastCheck       time.Time
-	SentSSLWarning  bool 
+	LastError       string // Error from the last check, included in alerts
+	LastHeartbeat   time.Time // Push monitors: when the last ping arrived
+	LastMessage     string    // Push monitors: msg sent with the last ping
+	PushFailed      bool      // Push monitors: the last ping reported status=down
+	JobStarted      time.Time // Push monitors: start ping of a run still in progress
+	SentSSLWarning  bool // Also covers the domain expiry warning
+}
+
+// RedactedSecret replaces credentials wherever sites leave the process in cleartext.
+const RedactedSecret = "********"
+
+// Redacted returns a copy of the site safe to show outside the engine. The push
+// token is dropped entirely: anyone holding it can report heartbeats.
+func (s Site) Redacted() Site {
+	s.Token = ""
+	for _, v := range []*string{&s.AuthPass, &s.AuthToken, &s.ClientKey, &s.DSN} {
+		if *v != "" { *v = RedactedSecret }
+	}
+	s.ProxyURL = redactURL(s.ProxyURL)
+	s.Steps = RedactSteps(s.Steps)
+	return s
+}
+
+// redactURL masks the password in a URL's userinfo. Unparsable values are hidden whole.
+func redactURL(raw string) string {
+	if raw == "" { return "" }
+	u, err := url.Parse(raw)
+	if err != nil { return RedactedSecret }
+	return u.Redacted()
+}
+
+// DependsOn reports whether site from reaches target by following parent
+// links through sites. Saving a parent that depends on the site itself would
+// create a cycle.
+func DependsOn(sites []Site, from, target int) bool {
+	parents := make(map[int][]int, len(sites))
+	for _, s := range sites { parents[s.ID] = s.ParentIDs }
+	seen := make(map[int]bool)
+	queue := []int{from}
+	for len(queue) > 0 {
+		id := queue[0]; queue = queue[1:]
+		if id == target { return true }
+		if seen[id] { continue }
+		seen[id] = true
+		queue = append(queue, parents[id]...)
+	}
+	return false
+}
+
+// GroupContains reports whether group from lists target as a member, directly
+// or through nested groups. Adding a member that contains the group itself
+// would create a cycle.
+func GroupContains(sites []Site, from, target int) bool {
+	members := make(map[int][]int, len(sites))
+	for _, s := range sites {
+		for _, m := range s.Members { members[s.ID] = append(members[s.ID], m.ID) }
+	}
+	seen := make(map[int]bool)
+	queue := []int{from}
+	for len(queue) > 0 {
+		id := queue[0]; queue = queue[1:]
+		if id == target { return true }
+		if seen[id] { continue }
+		seen[id] = true
+		queue = append(queue, members[id]...)
+	}
+	return false
+}
+
+// Target is the address shown for a site, without credentials.
+func (s Site) Target() string {
+	if s.URL == "" && len(s.Steps) > 0 { return s.Steps[0].URL }
+	if s.Type == "group" { return strconv.Itoa(len(s.Members)) + " members, " + s.Rule() }
+	if s.DSN == "" { return s.URL }
+	if u, err := url.Parse(s.DSN); err == nil && u.Scheme != "" && u.Host != "" { return u.Scheme + "://" + u.Host + u.Path }
+	if i := strings.LastIndex(s.DSN, "@"); i >= 0 { return s.DSN[i+1:] } // user:pass@tcp(host)/db
+	var kept []string
+	for _, kv := range strings.Fields(s.DSN) { // key=value form
+		if !strings.HasPrefix(strings.ToLower(kv), "password=") { kept = append(kept, kv) }
+	}
+	return strings.Join(kept, " ")
+}
+
+// MatchesTags reports whether the site carries every selector. A selector is a
+// full tag ("env:prod") or a bare key ("env") matching any of its values.
+func (s Site) MatchesTags(selectors []string) bool {
+	for _, sel := range selectors {
+		if !slices.ContainsFunc(s.Tags, func(t string) bool { return t == sel || strings.HasPrefix(t, sel+":") }) { return false }
+	}
+	return true
+}
+
+// TagValue returns the value of the site's first "key:value" tag, or "".
+func (s Site) TagValue(key string) string {
+	for _, t := range s.Tags {
+		if v, ok := strings.CutPrefix(t, key+":"); ok { return v }
+	}
+	return ""
+}
+
+// Rule returns the group aggregation rule with its default applied.
+func (s Site) Rule() string {
+	if s.GroupRule == "" { return "all" }
+	return s.GroupRule
+}
+
+// GroupMember is a monitor inside a group. Weight only matters for the
+// weighted rule; 0 counts as 1.
+type GroupMember struct {
+	ID     int
+	Weight int
+}
+
+// Step is one request of a transaction monitor. "{{name}}" in the URL,
+// headers or body is replaced by a variable extracted in an earlier step.
+type Step struct {
+	Name    string            `json:"name,omitempty"`
+	Method  string            `json:"method,omitempty"` // Default GET, or POST with a body
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"` // Values are secret
+	Body    string            `json:"body,omitempty"`    // Secret
+	Extract []Extract         `json:"extract,omitempty"`
+	Assert  []Assertion       `json:"assert,omitempty"` // Default: status < 400
+}
+
+// Extract stores part of a response in a variable.
+type Extract struct {
+	Var  string `json:"var"`
+	From string `json:"from"` // "json" (dotted path), "regex" (first group), "cookie" or "header"
+	Path string `json:"path"`
+}
+
+// Assertion checks a response. Expect uses the probe syntax ("= 200", "< 500")
+// except for "body", where it is a regex.
+type Assertion struct {
+	Check  string `json:"check"` // "status", "body", "json", "header" or "latency" (ms)
+	Path   string `json:"path,omitempty"`
+	Expect string `json:"expect"`
+}
+
+// RedactSteps returns a copy of steps with bodies and header values hidden.
+func RedactSteps(steps []Step) []Step {
+	if steps == nil { return nil }
+	out := make([]Step, len(steps))
+	for i, st := range steps {
+		if st.Body != "" { st.Body = RedactedSecret }
+		if st.Headers != nil {
+			h := make(map[string]string, len(st.Headers))
+			for k := range st.Headers { h[k] = RedactedSecret }
+			st.Headers = h
+		}
+		out[i] = st
+	}
+	return out
+}
+
+// SiteState is the slice of a site's runtime state that survives restarts.
+type SiteState struct {
+	SiteID         int
+	Status         string
+	FailureCount   int
+	LastCheck      time.Time
+	LastHeartbeat  time.Time
+	SentSSLWarning bool
+	LastMessage    string
+	PushFailed     bool
+	JobStarted     time.Time
 }
 
 type AlertConfig struct {
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// --- BANNER MONITORS ---
+// ssh reads the server's identification string; smtp reads the greeting and
+// runs EHLO, upgrading with STARTTLS when CheckSSL is set (smtps:// connects
+// with TLS directly). Expect, when set, must appear in the banner.
+
+var defaultPorts = map[string]string{"ssh": "22", "smtp": "25", "smtps": "465"}
+
+func checkBanner(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(site)); defer cancel()
+	site.HasSSL, site.CertExpiry = false, time.Time{}
+	start := time.Now()
+	res := CheckResult{Status: "UP"}
+	var banner string
+	var err error
+	if site.Type == "ssh" { banner, err = sshBanner(ctx, site.URL) } else { banner, err = smtpBanner(ctx, &site, &res) }
+	res.Latency = time.Since(start)
+	if banner != "" { site.Banner = banner }
+	if err == nil && site.Expect != "" && !strings.Contains(banner, site.Expect) { err = fmt.Errorf("banner %q does not contain %q", banner, site.Expect) }
+	if err != nil { res.Status = "DOWN"; res.Error = err.Error() }
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+// bannerAddr turns "host", "host:port" or "scheme://host[:port]" into a dial
+// address, reporting the scheme that was given (defaulting to kind).
+func bannerAddr(target, kind string) (addr, scheme string, err error) {
+	scheme = kind
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" { return "", "", fmt.Errorf("invalid address %q", target) }
+		scheme, target = u.Scheme, u.Host
+		if _, ok := defaultPorts[scheme]; !ok { return "", "", fmt.Errorf("unsupported scheme %q", scheme) }
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil { target = net.JoinHostPort(strings.Trim(target, "[]"), defaultPorts[scheme]) }
+	return target, scheme, nil
+}
+
+func dialBanner(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil { return nil, err }
+	if deadline, ok := ctx.Deadline(); ok { conn.SetDeadline(deadline) }
+	return conn, nil
+}
+
+// sshBanner returns the identification string ("SSH-2.0-OpenSSH_9.6"). Servers
+// may send other lines first (RFC 4253 section 4.2).
+func sshBanner(ctx context.Context, target string) (string, error) {
+	addr, _, err := bannerAddr(target, "ssh")
+	if err != nil { return "", err }
+	conn, err := dialBanner(ctx, addr)
+	if err != nil { return "", err }
+	defer conn.Close()
+	r := bufio.NewReaderSize(conn, 512)
+	for i := 0; i < 20; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil { return "", fmt.Errorf("no SSH identification: %w", err) }
+		line = strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(line, "SSH-") { continue }
+		if !strings.HasPrefix(line, "SSH-2.0-") && !strings.HasPrefix(line, "SSH-1.99-") { return line, fmt.Errorf("unsupported SSH protocol version in %q", line) }
+		conn.Write([]byte("SSH-2.0-GoUpkeep\r\n")) // Identify ourselves so the server logs a clean disconnect
+		return line, nil
+	}
+	return "", errors.New("no SSH identification string")
+}
+
+// smtpBanner returns the 220 greeting after a successful EHLO (and STARTTLS when requested).
+func smtpBanner(ctx context.Context, site *models.Site, res *CheckResult) (string, error) {
+	addr, scheme, err := bannerAddr(site.URL, "smtp")
+	if err != nil { return "", err }
+	host, _, _ := net.SplitHostPort(addr)
+	conn, err := dialBanner(ctx, addr)
+	if err != nil { return "", err }
+	defer func() { conn.Close() }()
+	tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: true}
+	if scheme == "smtps" {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil { return "", err }
+		conn = tlsConn
+		state := tlsConn.ConnectionState(); trackCert(site, res, &state)
+	}
+
+	tp := textproto.NewConn(conn)
+	code, banner, err := tp.ReadResponse(220)
+	if err != nil { return banner, smtpError(code, banner, err) }
+	banner = strings.SplitN(banner, "\n", 2)[0]
+	ehlo := func() (string, error) {
+		if err := tp.PrintfLine("EHLO upkeep.local"); err != nil { return "", err }
+		code, msg, err := tp.ReadResponse(250)
+		if err != nil { return "", fmt.Errorf("EHLO: %w", smtpError(code, msg, err)) }
+		return msg, nil
+	}
+	exts, err := ehlo()
+	if err != nil { return banner, err }
+
+	if site.CheckSSL && scheme != "smtps" {
+		if !hasSMTPExtension(exts, "STARTTLS") { return banner, errors.New("server does not offer STARTTLS") }
+		if err := tp.PrintfLine("STARTTLS"); err != nil { return banner, err }
+		if code, msg, err := tp.ReadResponse(220); err != nil { return banner, fmt.Errorf("STARTTLS: %w", smtpError(code, msg, err)) }
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil { return banner, fmt.Errorf("STARTTLS: %w", err) }
+		conn = tlsConn
+		state := tlsConn.ConnectionState(); trackCert(site, res, &state)
+		tp = textproto.NewConn(conn)
+		if _, err := ehlo(); err != nil { return banner, err }
+	}
+	tp.PrintfLine("QUIT")
+	tp.ReadResponse(221)
+	return banner, nil
+}
+
+func hasSMTPExtension(ehlo, ext string) bool {
+	for _, line := range strings.Split(ehlo, "\n") {
+		if f := strings.Fields(line); len(f) > 0 && strings.EqualFold(f[0], ext) { return true }
+	}
+	return false
+}
+
+func smtpError(code int, msg string, err error) error {
+	if code == 0 { return err }
+	return fmt.Errorf("%d %s", code, msg)
+}
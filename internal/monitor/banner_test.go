@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"go-upkeep/internal/models"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBannerAddr(t *testing.T) {
+	tests := []struct{ target, kind, addr, scheme, err string }{
+		{"mail.test", "smtp", "mail.test:25", "smtp", ""},
+		{"mail.test:587", "smtp", "mail.test:587", "smtp", ""},
+		{"smtps://mail.test", "smtp", "mail.test:465", "smtps", ""},
+		{"ssh://host.test:2222", "ssh", "host.test:2222", "ssh", ""},
+		{"host.test", "ssh", "host.test:22", "ssh", ""},
+		{"[::1]", "ssh", "[::1]:22", "ssh", ""},
+		{"ftp://host.test", "ssh", "", "", "unsupported scheme"},
+		{"smtp://", "smtp", "", "", "invalid address"},
+	}
+	for _, tt := range tests {
+		addr, scheme, err := bannerAddr(tt.target, tt.kind)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) { t.Errorf("bannerAddr(%q) err = %v, want %q", tt.target, err, tt.err) }
+			continue
+		}
+		if err != nil || addr != tt.addr || scheme != tt.scheme { t.Errorf("bannerAddr(%q) = %q, %q, %v", tt.target, addr, scheme, err) }
+	}
+}
+
+func TestHasSMTPExtension(t *testing.T) {
+	ehlo := "mail.test greets you\nSIZE 1000\nstarttls\nAUTH PLAIN"
+	for ext, want := range map[string]bool{"STARTTLS": true, "SIZE": true, "AUTH": true, "PIPELINING": false, "mail.test": true} {
+		if got := hasSMTPExtension(ehlo, ext); got != want { t.Errorf("hasSMTPExtension(%q) = %v", ext, got) }
+	}
+}
+
+// selfSigned returns a throwaway certificate for TLS stubs.
+func selfSigned(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil { t.Fatal(err) }
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "stub.test"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(24 * time.Hour).Truncate(time.Second)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil { t.Fatal(err) }
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// serveStub runs handle for every connection accepted on a local port.
+func serveStub(t *testing.T, handle func(net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil { return }
+			go func() { defer conn.Close(); handle(conn) }()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestSSHBannerStub(t *testing.T) {
+	stub := func(lines string) string {
+		return serveStub(t, func(c net.Conn) {
+			c.Write([]byte(lines))
+			if strings.Contains(lines, "SSH-") { bufio.NewReader(c).ReadString('\n') } // Wait for our identification
+		})
+	}
+	tests := []struct{ addr, banner, status, err string }{
+		{stub("Welcome\r\nSSH-2.0-OpenSSH_9.6\r\n"), "SSH-2.0-OpenSSH_9.6", "UP", ""},
+		{stub("SSH-1.99-Legacy\r\n"), "SSH-1.99-Legacy", "UP", ""},
+		{stub("SSH-1.5-Old\r\n"), "SSH-1.5-Old", "DOWN", "unsupported SSH protocol version"},
+		{stub("HTTP/1.1 400 Bad Request\r\n"), "", "DOWN", "no SSH identification"},
+	}
+	for _, tt := range tests {
+		site, res := checkBanner(context.Background(), models.Site{Type: "ssh", URL: tt.addr, Timeout: 2})
+		if site.Banner != tt.banner || res.Status != tt.status || !strings.HasPrefix(res.Error, tt.err) { t.Errorf("got %q %s %q, want %q %s %q", site.Banner, res.Status, res.Error, tt.banner, tt.status, tt.err) }
+	}
+}
+
+// smtpStub greets, answers EHLO (offering STARTTLS when cert is set), upgrades on
+// STARTTLS and says goodbye on QUIT. With implicit the whole session is TLS.
+func smtpStub(t *testing.T, cert *tls.Certificate, implicit bool) string {
+	return serveStub(t, func(c net.Conn) {
+		secure := implicit
+		if implicit { c = tls.Server(c, &tls.Config{Certificates: []tls.Certificate{*cert}}) }
+		r := bufio.NewReader(c)
+		c.Write([]byte("220 mail.test ESMTP Stub\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil { return }
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO") && cert != nil && !secure: c.Write([]byte("250-mail.test\r\n250-SIZE 1000\r\n250 STARTTLS\r\n"))
+			case strings.HasPrefix(cmd, "EHLO"): c.Write([]byte("250-mail.test\r\n250 SIZE 1000\r\n"))
+			case cmd == "STARTTLS" && cert != nil:
+				c.Write([]byte("220 Go ahead\r\n"))
+				c = tls.Server(c, &tls.Config{Certificates: []tls.Certificate{*cert}}); r = bufio.NewReader(c); secure = true
+			case cmd == "QUIT": c.Write([]byte("221 Bye\r\n")); return
+			default: c.Write([]byte("502 Command not implemented\r\n"))
+			}
+		}
+	})
+}
+
+func TestSMTPBannerStub(t *testing.T) {
+	cert := selfSigned(t)
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	plain, starttls, implicit := smtpStub(t, nil, false), smtpStub(t, &cert, false), smtpStub(t, &cert, true)
+	tests := []struct {
+		site        models.Site
+		status, err string
+		ssl         bool
+	}{
+		{models.Site{URL: plain}, "UP", "", false},
+		{models.Site{URL: plain, Expect: "Postfix"}, "DOWN", `banner "mail.test ESMTP Stub" does not contain "Postfix"`, false},
+		{models.Site{URL: plain, CheckSSL: true}, "DOWN", "server does not offer STARTTLS", false},
+		{models.Site{URL: starttls, CheckSSL: true, Expect: "ESMTP"}, "UP", "", true},
+		{models.Site{URL: "smtps://" + implicit, CheckSSL: true}, "UP", "", true},
+	}
+	for _, tt := range tests {
+		tt.site.Type = "smtp"; tt.site.Timeout = 2
+		site, res := checkBanner(context.Background(), tt.site)
+		if res.Status != tt.status || res.Error != tt.err || site.Banner != "mail.test ESMTP Stub" { t.Errorf("%s: got %s %q banner %q", tt.site.URL, res.Status, res.Error, site.Banner) }
+		if site.HasSSL != tt.ssl || (tt.ssl && !site.CertExpiry.Equal(leaf.NotAfter)) { t.Errorf("%s: HasSSL %v expiry %v", tt.site.URL, site.HasSSL, site.CertExpiry) }
+	}
+}
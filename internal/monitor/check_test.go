@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"go-upkeep/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckNow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	setLive(t, models.Site{ID: 1, Name: "api", Type: "http", URL: srv.URL, Status: "PENDING"})
+
+	if _, err := CheckNow(2); !errors.Is(err, ErrSiteNotFound) { t.Errorf("unknown site: err %v, want ErrSiteNotFound", err) }
+	SetEngineActive(false)
+	_, err := CheckNow(1)
+	SetEngineActive(true)
+	if !errors.Is(err, ErrEnginePassive) { t.Errorf("passive engine: err %v, want ErrEnginePassive", err) }
+
+	res, err := CheckNow(1)
+	if err != nil || res.Status != "UP" || res.StatusCode != 200 || res.CheckedAt.IsZero() { t.Fatalf("CheckNow = %+v, %v", res, err) }
+	if got := LiveState[1].Status; got != "UP" { t.Errorf("live status %s, want UP", got) }
+}
+
+func TestCheckNowWaitsForRunningCheck(t *testing.T) {
+	setLive(t, models.Site{ID: 1, Name: "job", Type: "push", Interval: 60, Status: "UP", LastHeartbeat: time.Now()})
+	unlock, _ := lockSite(context.Background(), 1) // A scheduled check in flight
+	done := make(chan error, 1)
+	go func() { _, err := CheckNow(1); done <- err }()
+	select {
+	case <-done: t.Fatal("CheckNow ran alongside a running check")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	if err := <-done; err != nil { t.Errorf("CheckNow after the running check: %v", err) }
+
+	// A check cancelled while waiting gives up without probing
+	unlock, _ = lockSite(context.Background(), 1); defer unlock()
+	ctx, cancel := context.WithCancel(context.Background()); cancel()
+	if _, err := checkByID(ctx, 1); !errors.Is(err, context.Canceled) { t.Errorf("cancelled wait: err %v", err) }
+}
+
+// Overlapping checks each see the previous one's result, so every failure counts.
+func TestConcurrentChecksCountEachFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond); w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	setLive(t, models.Site{ID: 1, Name: "api", Type: "http", URL: srv.URL, Status: "UP", MaxRetries: 5})
+
+	var wg sync.WaitGroup
+	for range 3 { wg.Go(func() { CheckNow(1) }) }
+	wg.Wait()
+	if got := LiveState[1].FailureCount; got != 3 { t.Errorf("FailureCount %d after 3 failed checks, want 3", got) }
+}
+
+func TestHeartbeatAfterRunningCheck(t *testing.T) {
+	setLive(t, models.Site{ID: 1, Name: "job", Type: "push", Token: "tok", Interval: 60, Status: "UP", LastHeartbeat: time.Now()})
+	Mutex.Lock(); tokens["tok"] = 1; Mutex.Unlock()
+	t.Cleanup(func() { Mutex.Lock(); delete(tokens, "tok"); Mutex.Unlock() })
+
+	unlock, _ := lockSite(context.Background(), 1)
+	done := make(chan bool, 1)
+	go func() { done <- RecordHeartbeat("tok", Push{Down: true, Message: "disk full"}) }()
+	time.Sleep(50 * time.Millisecond)
+	Mutex.Lock(); s := LiveState[1]; s.LastError = "stale"; LiveState[1] = s; Mutex.Unlock() // The running check writes back
+	unlock()
+	if !<-done { t.Fatal("heartbeat rejected") }
+	if got := LiveState[1]; got.Status != "DOWN" || got.LastMessage != "disk full" { t.Errorf("after heartbeat: status %s, message %q", got.Status, got.LastMessage) }
+	if RecordHeartbeat("other", Push{}) { t.Error("unknown token accepted") }
+}
@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- CRON SCHEDULES ---
+// Standard 5-field expressions (minute hour day-of-month month day-of-week) with
+// lists, ranges, steps, month/day names and the usual @-macros. As in Vixie cron,
+// a job restricted by both day fields runs when either matches.
+
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Bit n set = value n allowed
+	domAny, dowAny                bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly": "0 0 1 1 *", "@annually": "0 0 1 1 *", "@monthly": "0 0 1 * *",
+	"@weekly": "0 0 * * 0", "@daily": "0 0 * * *", "@midnight": "0 0 * * *", "@hourly": "0 * * * *",
+}
+
+var (
+	monthNames = []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// ParseCron parses a 5-field cron expression or macro.
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := cronMacros[strings.ToLower(expr)]; ok { expr = m }
+	fields := strings.Fields(expr)
+	if len(fields) != 5 { return nil, fmt.Errorf("cron: expected 5 fields, got %d", len(fields)) }
+
+	c := &CronSchedule{domAny: strings.HasPrefix(fields[2], "*"), dowAny: strings.HasPrefix(fields[4], "*")}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil { return nil, fmt.Errorf("cron minute: %w", err) }
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil { return nil, fmt.Errorf("cron hour: %w", err) }
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil { return nil, fmt.Errorf("cron day of month: %w", err) }
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil { return nil, fmt.Errorf("cron month: %w", err) }
+	if c.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil { return nil, fmt.Errorf("cron day of week: %w", err) }
+	if c.dow&(1<<7) != 0 { c.dow |= 1 } // 7 is Sunday too
+	return c, nil
+}
+
+func parseCronField(field string, lo, hi int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s < 1 { return 0, fmt.Errorf("invalid step in %q", part) }
+			rng, step = part[:i], s
+		}
+		start, end := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if start, err = cronValue(a, lo, hi, names); err != nil { return 0, err }
+			end = start
+			if isRange {
+				if end, err = cronValue(b, lo, hi, names); err != nil { return 0, err }
+				if end < start { return 0, fmt.Errorf("invalid range %q", rng) }
+			} else if step > 1 {
+				end = hi // "5/15" means every 15 starting at 5
+			}
+		}
+		for v := start; v <= end; v += step { bits |= 1 << v }
+	}
+	return bits, nil
+}
+
+func cronValue(s string, lo, hi int, names []string) (int, error) {
+	for i, n := range names { if n != "" && strings.EqualFold(s, n) { return i, nil } }
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi { return 0, fmt.Errorf("value %q out of range %d-%d", s, lo, hi) }
+	return v, nil
+}
+
+var errNoCronMatch = errors.New("cron: schedule never matches")
+
+// Next returns the first scheduled time strictly after t, in t's location.
+func (c *CronSchedule) Next(t time.Time) (time.Time, error) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t, nil
+		}
+		// Wall times skipped by a DST change can normalize backwards; always move forward
+		if !next.After(t) { next = t.Add(time.Hour) }
+		t = next
+	}
+	return time.Time{}, errNoCronMatch
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny { return dom && dow }
+	return dom || dow
+}
@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	bits := func(vals ...int) (b uint64) { for _, v := range vals { b |= 1 << v }; return b }
+	tests := []struct {
+		field  string
+		lo, hi int
+		names  []string
+		want   uint64
+	}{
+		{"5", 0, 59, nil, bits(5)},
+		{"1-3", 0, 59, nil, bits(1, 2, 3)},
+		{"*/20", 0, 59, nil, bits(0, 20, 40)},
+		{"10-30/10", 0, 59, nil, bits(10, 20, 30)},
+		{"50/5", 0, 59, nil, bits(50, 55)},
+		{"1,4,9-10", 0, 23, nil, bits(1, 4, 9, 10)},
+		{"jan,MAR-apr", 1, 12, monthNames, bits(1, 3, 4)},
+		{"mon-fri/2", 0, 7, dayNames, bits(1, 3, 5)},
+	}
+	for _, tt := range tests {
+		got, err := parseCronField(tt.field, tt.lo, tt.hi, tt.names)
+		if err != nil || got != tt.want { t.Errorf("parseCronField(%q) = %b, %v, want %b", tt.field, got, err, tt.want) }
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "0 * 0 * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "* * * * 8"} {
+		if _, err := ParseCron(expr); err == nil { t.Errorf("ParseCron(%q) accepted", expr) }
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time { v, _ := time.Parse("2006-01-02 15:04", s); return v }
+	tests := []struct{ expr, from, want string }{
+		{"*/15 * * * *", "2026-01-15 10:07", "2026-01-15 10:15"},
+		{"*/15 * * * *", "2026-01-15 10:15", "2026-01-15 10:30"}, // Strictly after
+		{"0 9-17/4 * * *", "2026-01-15 10:07", "2026-01-15 13:00"},
+		{"30 8 * * mon-fri", "2026-01-16 09:00", "2026-01-19 08:30"},
+		{"0 0 * * 7", "2026-01-15 10:07", "2026-01-18 00:00"},
+		{"0 0 * jan,jul sun", "2026-02-01 00:00", "2026-07-05 00:00"},
+		{"@hourly", "2026-01-15 23:30", "2026-01-16 00:00"},
+		{"@yearly", "2026-12-31 23:59", "2027-01-01 00:00"},
+		// Both day fields restricted: either one matches
+		{"0 0 20 * mon", "2026-01-15 10:07", "2026-01-19 00:00"},
+		{"0 0 20 * mon", "2026-01-19 00:00", "2026-01-20 00:00"},
+		// Only one restricted: the other must not widen the match
+		{"0 0 20 * *", "2026-01-15 10:07", "2026-01-20 00:00"},
+		// Months without the day are skipped
+		{"0 0 1,15 * *", "2026-01-15 10:07", "2026-02-01 00:00"},
+		{"0 0 31 * *", "2026-01-31 10:00", "2026-03-31 00:00"},
+		{"0 12 29 2 *", "2026-01-01 00:00", "2028-02-29 12:00"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil { t.Errorf("ParseCron(%q): %v", tt.expr, err); continue }
+		got, err := c.Next(at(tt.from))
+		if err != nil || !got.Equal(at(tt.want)) { t.Errorf("%q after %s = %v, %v, want %s", tt.expr, tt.from, got, err, tt.want) }
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil { t.Fatal(err) }
+	if _, err := c.Next(time.Now()); !errors.Is(err, errNoCronMatch) { t.Errorf("Feb 30: err = %v, want errNoCronMatch", err) }
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go-upkeep/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+// --- DATABASE MONITORS ---
+// Each check opens a fresh connection so connect failures are caught, runs the
+// probe query and optionally asserts on the first column of the first row.
+
+var sqlDrivers = map[string]string{"postgres": "postgres", "mysql": "mysql"}
+
+const defaultSQLProbe = "SELECT 1"
+
+func checkDatabase(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(site)); defer cancel()
+	start := time.Now()
+	var value string
+	var err error
+	if site.Type == "redis" { value, err = probeRedis(ctx, site.DSN, site.Query) } else { value, err = probeSQL(ctx, site) }
+	res := CheckResult{Status: "UP", Latency: time.Since(start)}
+	if err == nil { err = assertScalar(value, site.Expect) }
+	if err != nil { res.Status = "DOWN"; res.Error = err.Error() }
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+func probeSQL(ctx context.Context, site models.Site) (string, error) {
+	db, err := sql.Open(sqlDrivers[site.Type], site.DSN)
+	if err != nil { return "", err }
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	query := site.Query
+	if strings.TrimSpace(query) == "" { query = defaultSQLProbe }
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil { return "", err }
+	defer rows.Close()
+	if !rows.Next() { return "", rows.Err() } // Statements without rows still prove the server answers
+	cols, err := rows.Columns()
+	if err != nil { return "", err }
+	vals := make([]sql.RawBytes, len(cols)); ptrs := make([]any, len(cols))
+	for i := range vals { ptrs[i] = &vals[i] }
+	if err := rows.Scan(ptrs...); err != nil { return "", err }
+	if len(vals) == 0 { return "", nil }
+	return string(vals[0]), nil
+}
+
+func checkTimeout(site models.Site) time.Duration {
+	if site.Timeout > 0 { return time.Duration(site.Timeout) * time.Second }
+	return defaultTimeout
+}
+
+// assertScalar checks a probe result against expect: an optional operator
+// (=, !=, <, <=, >, >=) followed by a value, e.g. "< 30". Ordering comparisons
+// are numeric; equality compares numbers when both sides parse, text otherwise.
+func assertScalar(value, expect string) error {
+	expect = strings.TrimSpace(expect)
+	if expect == "" { return nil }
+	op := "="
+	for _, o := range []string{"<=", ">=", "!=", "==", "<", ">", "="} {
+		if strings.HasPrefix(expect, o) { op = o; expect = strings.TrimSpace(expect[len(o):]); break }
+	}
+	if op == "==" { op = "=" }
+
+	got, gotErr := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	want, wantErr := strconv.ParseFloat(expect, 64)
+	numeric := gotErr == nil && wantErr == nil
+	var ok bool
+	switch op {
+	case "=": ok = value == expect || (numeric && got == want)
+	case "!=": ok = value != expect && !(numeric && got == want)
+	default:
+		if !numeric { return fmt.Errorf("result %q is not comparable with %s %s", value, op, expect) }
+		switch op {
+		case "<": ok = got < want
+		case "<=": ok = got <= want
+		case ">": ok = got > want
+		case ">=": ok = got >= want
+		}
+	}
+	if !ok { return fmt.Errorf("result %q does not satisfy %s %s", value, op, expect) }
+	return nil
+}
@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"context"
+	"go-upkeep/internal/models"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSite registers SQLite as a stand-in SQL monitor type and returns a site
+// probing a fresh database file.
+func sqliteSite(t *testing.T) models.Site {
+	sqlDrivers["sqlite"] = "sqlite3"
+	t.Cleanup(func() { delete(sqlDrivers, "sqlite") })
+	return models.Site{Type: "sqlite", DSN: filepath.Join(t.TempDir(), "probe.db")}
+}
+
+func TestProbeSQL(t *testing.T) {
+	site := sqliteSite(t)
+	tests := []struct {
+		query, want string
+		fails       bool
+	}{
+		{"", "1", false}, // The default probe
+		{"SELECT 42, 'second column'", "42", false},
+		{"SELECT NULL", "", false},
+		{"CREATE TABLE IF NOT EXISTS t (x)", "", false}, // No rows still proves the server answers
+		{"SELECT 'x' WHERE 1 = 0", "", false},
+		{"SELECT * FROM missing", "", true},
+	}
+	for _, tt := range tests {
+		site.Query = tt.query
+		got, err := probeSQL(context.Background(), site)
+		if (err != nil) != tt.fails || got != tt.want { t.Errorf("probeSQL(%q) = %q, %v", tt.query, got, err) }
+	}
+
+	ctx, cancel := context.WithCancel(context.Background()); cancel()
+	if _, err := probeSQL(ctx, site); err == nil { t.Error("probe with a cancelled context succeeded") }
+}
+
+func TestCheckDatabaseAssertion(t *testing.T) {
+	site := sqliteSite(t)
+	site.Query = "SELECT 25"
+	tests := []struct {
+		expect, status string
+	}{
+		{"", "UP"},
+		{"< 30", "UP"},
+		{"25", "UP"},
+		{">= 30", "DOWN"},
+		{"ok", "DOWN"},
+	}
+	for _, tt := range tests {
+		site.Expect = tt.expect
+		_, res := checkDatabase(context.Background(), site)
+		if res.Status != tt.status { t.Errorf("expect %q: %s (%s), want %s", tt.expect, res.Status, res.Error, tt.status) }
+		if tt.status == "DOWN" && !strings.Contains(res.Error, `"25"`) { t.Errorf("expect %q: error %q does not name the result", tt.expect, res.Error) }
+	}
+}
+
+func TestAssertScalar(t *testing.T) {
+	tests := []struct {
+		value, expect string
+		pass          bool
+	}{
+		{"anything", "", true},
+		{"200", "200", true},
+		{"200", "== 200.0", true},
+		{"200", "!= 201", true},
+		{"200", "!=200", false},
+		{"ok", "ok", true},
+		{"ok", "= fail", false},
+		{"350", "< 400", true},
+		{"400", "< 400", false},
+		{"400", "<= 400", true},
+		{"12.5", "> 12", true},
+		{"12", ">= 12.5", false},
+		{"abc", "< 5", false}, // Ordering needs numbers
+	}
+	for _, tt := range tests {
+		if err := assertScalar(tt.value, tt.expect); (err == nil) != tt.pass { t.Errorf("assertScalar(%q, %q) = %v, want pass %v", tt.value, tt.expect, err, tt.pass) }
+	}
+}
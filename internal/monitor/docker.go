@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- DOCKER ---
+// Inspects a container (URL holds its name or ID) through the Engine API on a
+// unix socket. Not running, restarting or an unhealthy healthcheck is DOWN.
+
+// DockerSocket is the Engine API socket. Set before StartEngine.
+var DockerSocket = "/var/run/docker.sock"
+
+var dockerClient = sync.OnceValue(func() *http.Client {
+	t := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", strings.TrimPrefix(DockerSocket, "unix://"))
+		},
+		MaxIdleConns: 2, IdleConnTimeout: 30 * time.Second,
+	}
+	return &http.Client{Transport: t}
+})
+
+type containerInfo struct {
+	Name         string
+	RestartCount int
+	State        struct {
+		Status     string
+		Running    bool
+		Restarting bool
+		ExitCode   int
+		Error      string
+		Health     *struct {
+			Status string
+			Log    []struct{ ExitCode int; Output string }
+		}
+	}
+}
+
+func checkDocker(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(site)); defer cancel()
+	start := time.Now()
+	info, err := inspectContainer(ctx, strings.TrimPrefix(strings.TrimSpace(site.URL), "/"))
+	res := CheckResult{Status: "UP", Latency: time.Since(start)}
+	if err != nil {
+		res.Status = "DOWN"; res.Error = err.Error()
+	} else {
+		site.RestartCount = info.RestartCount
+		site.ContainerState = info.State.Status
+		if h := info.State.Health; h != nil { site.ContainerState += " (" + h.Status + ")" }
+		if problem := containerProblem(info); problem != "" {
+			res.Status = "DOWN"; res.Error = fmt.Sprintf("%s, %d restarts", problem, info.RestartCount)
+		}
+	}
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+// containerProblem describes why a container counts as down, or "" when it is healthy.
+func containerProblem(info containerInfo) string {
+	st := info.State
+	switch {
+	case st.Restarting:
+		return "container is restarting"
+	case !st.Running:
+		msg := fmt.Sprintf("container is %s (exit code %d)", st.Status, st.ExitCode)
+		if st.Error != "" { msg += ": " + st.Error }
+		return msg
+	case st.Health != nil && st.Health.Status == "unhealthy":
+		msg := "healthcheck unhealthy"
+		if n := len(st.Health.Log); n > 0 {
+			if out := strings.TrimSpace(st.Health.Log[n-1].Output); out != "" { msg += ": " + limitReply([]byte(out)) }
+		}
+		return msg
+	}
+	return ""
+}
+
+func inspectContainer(ctx context.Context, name string) (containerInfo, error) {
+	var info containerInfo
+	if name == "" { return info, errors.New("container name or ID is required") }
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://docker/containers/"+url.PathEscape(name)+"/json", nil)
+	if err != nil { return info, err }
+	resp, err := dockerClient().Do(req)
+	if err != nil { return info, fmt.Errorf("docker API: %w", err) }
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil { return info, err }
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct{ Message string }
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" { return info, fmt.Errorf("docker API: %s", apiErr.Message) }
+		return info, fmt.Errorf("docker API: HTTP %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, &info); err != nil { return info, fmt.Errorf("docker API: %w", err) }
+	return info, nil
+}
@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"context"
+	"go-upkeep/internal/models"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+// fakeEngine serves container inspection from a unix socket in a temp dir and
+// points DockerSocket at it for the test.
+func fakeEngine(t *testing.T, containers map[string]string) {
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil { t.Skip("unix sockets unavailable:", err) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/{name}/json", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := containers[r.PathValue("name")]
+		w.Header().Set("Content-Type", "application/json")
+		if !ok { w.WriteHeader(404); w.Write([]byte(`{"message":"No such container: ` + r.PathValue("name") + `"}`)); return }
+		w.Write([]byte(body))
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(l)
+	prev := DockerSocket; DockerSocket = sock
+	t.Cleanup(func() { srv.Close(); dockerClient().CloseIdleConnections(); DockerSocket = prev })
+}
+
+func TestDockerFakeEngine(t *testing.T) {
+	fakeEngine(t, map[string]string{
+		"web": `{"Name":"/web","RestartCount":0,"State":{"Status":"running","Running":true,"Health":{"Status":"healthy"}}}`,
+		"api": `{"Name":"/api","RestartCount":3,"State":{"Status":"running","Running":true,"Health":{"Status":"unhealthy","Log":[{"ExitCode":1,"Output":"old"},{"ExitCode":1,"Output":"curl: (7) refused\n"}]}}}`,
+		"job": `{"Name":"/job","RestartCount":1,"State":{"Status":"exited","Running":false,"ExitCode":2}}`,
+	})
+	tests := []struct{ name, status, err, state string }{
+		{"web", "UP", "", "running (healthy)"},
+		{"/api", "DOWN", "healthcheck unhealthy: curl: (7) refused, 3 restarts", "running (unhealthy)"},
+		{"job", "DOWN", "container is exited (exit code 2), 1 restarts", "exited"},
+		{"gone", "DOWN", "docker API: No such container: gone", ""},
+		{"", "DOWN", "container name or ID is required", ""},
+	}
+	for _, tt := range tests {
+		site, res := checkDocker(context.Background(), models.Site{Type: "docker", URL: tt.name})
+		if res.Status != tt.status || res.Error != tt.err || site.ContainerState != tt.state {
+			t.Errorf("%q: got %s %q state %q, want %s %q state %q", tt.name, res.Status, res.Error, site.ContainerState, tt.status, tt.err, tt.state)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- DOMAIN EXPIRY ---
+// Registration expiry comes from RDAP; when that fails the registry's WHOIS
+// server (found through the IANA referral) is asked instead. The site's URL
+// holds the domain name.
+
+// RDAPBaseURL and WHOISServer are where lookups start. Set before StartEngine;
+// pointing them at a local stub makes the monitor testable offline.
+var (
+	RDAPBaseURL = "https://rdap.org"
+	WHOISServer = "whois.iana.org:43"
+)
+
+// whoisExpiryKeys are the expiry labels used by common registries, lowercased.
+var whoisExpiryKeys = []string{"registry expiry date", "registrar registration expiration date", "expiration date", "expiry date", "expire date", "expires on", "expires", "paid-till", "renewal date"}
+
+var whoisDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05Z", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", "02-Jan-2006", "2006.01.02", "02.01.2006", "2006/01/02"}
+
+type domainInfo struct {
+	expiry    time.Time
+	registrar string
+}
+
+func checkDomain(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, 2*checkTimeout(site)); defer cancel() // Room for the WHOIS fallback
+	start := time.Now()
+	domain := domainName(site.URL)
+	info, err := lookupRDAP(ctx, domain)
+	if err != nil {
+		var werr error
+		if info, werr = lookupWHOIS(ctx, domain); werr != nil { err = fmt.Errorf("rdap: %v; whois: %v", err, werr) } else { err = nil }
+	}
+	res := CheckResult{Status: "UP", Latency: time.Since(start)}
+	if err != nil {
+		res.Status = "DOWN"; res.Error = err.Error()
+	} else {
+		site.DomainExpiry, site.Registrar = info.expiry, info.registrar
+		if time.Now().After(info.expiry) { res.Status = "DOWN"; res.Error = "domain registration expired " + info.expiry.Format(time.RFC3339) }
+	}
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+// domainName accepts a bare domain or a URL and returns the lowercased host.
+func domainName(s string) string {
+	s = strings.TrimSpace(s)
+	if u, err := url.Parse(s); err == nil && u.Host != "" { s = u.Hostname() }
+	return strings.TrimSuffix(strings.ToLower(s), ".")
+}
+
+func lookupRDAP(ctx context.Context, domain string) (domainInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(RDAPBaseURL, "/")+"/domain/"+url.PathEscape(domain), nil)
+	if err != nil { return domainInfo{}, err }
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+	req.Header.Set("User-Agent", "GoUpkeep/1.0")
+	resp, err := (&http.Client{}).Do(req) // Default transport: rdap.org redirects to the registry's server
+	if err != nil { return domainInfo{}, err }
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { return domainInfo{}, fmt.Errorf("HTTP %s", resp.Status) }
+
+	var doc struct {
+		Events []struct {
+			Action string `json:"eventAction"`
+			Date   string `json:"eventDate"`
+		} `json:"events"`
+		Entities []struct {
+			Roles []string          `json:"roles"`
+			VCard []json.RawMessage `json:"vcardArray"`
+		} `json:"entities"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil { return domainInfo{}, fmt.Errorf("invalid RDAP response: %w", err) }
+	var info domainInfo
+	for _, e := range doc.Events {
+		if e.Action != "expiration" { continue }
+		if info.expiry, err = time.Parse(time.RFC3339, e.Date); err != nil { return domainInfo{}, fmt.Errorf("invalid expiration date %q", e.Date) }
+	}
+	if info.expiry.IsZero() { return domainInfo{}, errors.New("no expiration event in RDAP response") }
+	for _, ent := range doc.Entities {
+		if len(ent.VCard) < 2 || !slices.Contains(ent.Roles, "registrar") { continue }
+		info.registrar = vcardName(ent.VCard[1])
+	}
+	return info, nil
+}
+
+// vcardName picks the "fn" property out of a jCard property list.
+func vcardName(props json.RawMessage) string {
+	var list [][]any
+	if json.Unmarshal(props, &list) != nil { return "" }
+	for _, p := range list {
+		if len(p) >= 4 && p[0] == "fn" {
+			if s, ok := p[3].(string); ok { return s }
+		}
+	}
+	return ""
+}
+
+// lookupWHOIS asks the configured server, following one "refer:" / "whois:" referral.
+func lookupWHOIS(ctx context.Context, domain string) (domainInfo, error) {
+	server := WHOISServer
+	for hop := 0; hop < 2; hop++ {
+		fields, err := whoisQuery(ctx, server, domain)
+		if err != nil { return domainInfo{}, err }
+		var info domainInfo
+		for _, key := range whoisExpiryKeys {
+			if v, ok := fields[key]; ok {
+				if info.expiry, err = parseWHOISDate(v); err != nil { return domainInfo{}, err }
+				break
+			}
+		}
+		if !info.expiry.IsZero() { info.registrar = fields["registrar"]; return info, nil }
+		next := fields["refer"]
+		if next == "" { next = fields["whois"] }
+		if next == "" || hop == 1 { break }
+		server = next
+		if _, _, err := net.SplitHostPort(server); err != nil { server = net.JoinHostPort(server, "43") }
+	}
+	return domainInfo{}, errors.New("no expiry date in WHOIS response")
+}
+
+// whoisQuery returns the "key: value" lines of a WHOIS reply with lowercased
+// keys, keeping the first value of each.
+func whoisQuery(ctx context.Context, server, domain string) (map[string]string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil { return nil, err }
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok { conn.SetDeadline(deadline) }
+	if _, err := conn.Write([]byte(domain + "\r\n")); err != nil { return nil, err }
+
+	fields := map[string]string{}
+	sc := bufio.NewScanner(io.LimitReader(conn, 1<<20))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		key = strings.ToLower(strings.TrimSpace(key)); value = strings.TrimSpace(value)
+		if !ok || key == "" || value == "" || strings.HasPrefix(key, "%") || strings.HasPrefix(key, "#") { continue }
+		if _, seen := fields[key]; !seen { fields[key] = value }
+	}
+	return fields, sc.Err()
+}
+
+func parseWHOISDate(v string) (time.Time, error) {
+	v = strings.TrimSpace(strings.TrimSuffix(v, "UTC"))
+	for _, layout := range whoisDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil { return t, nil }
+	}
+	return time.Time{}, fmt.Errorf("unrecognised WHOIS date %q", v)
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"go-upkeep/internal/models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWHOISDate(t *testing.T) {
+	want := time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []string{"2030-05-01T00:00:00Z", "2030-05-01T00:00:00.0Z", "2030-05-01 00:00:00 UTC", "2030-05-01", "01-May-2030", "2030.05.01", "01.05.2030", "2030/05/01", " 2030-05-01T00:00:00 "} {
+		if got, err := parseWHOISDate(v); err != nil || !got.Equal(want) { t.Errorf("parseWHOISDate(%q) = %v, %v", v, got, err) }
+	}
+	for _, v := range []string{"", "soon", "05/01/2030"} {
+		if _, err := parseWHOISDate(v); err == nil { t.Errorf("parseWHOISDate(%q) accepted", v) }
+	}
+}
+
+func TestVCardName(t *testing.T) {
+	tests := []struct{ props, want string }{
+		{`[["version",{},"text","4.0"],["fn",{},"text","Example Registrar, Inc."]]`, "Example Registrar, Inc."},
+		{`[["version",{},"text","4.0"]]`, ""},
+		{`[["fn",{},"text"]]`, ""},
+		{`[["fn",{},"text",42]]`, ""},
+		{`"vcard"`, ""},
+	}
+	for _, tt := range tests {
+		if got := vcardName(json.RawMessage(tt.props)); got != tt.want { t.Errorf("vcardName(%s) = %q, want %q", tt.props, got, tt.want) }
+	}
+}
+
+func TestDomainName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"example.com", "example.com"},
+		{" Example.COM. ", "example.com"},
+		{"https://www.example.com:8443/path?q=1", "www.example.com"},
+		{"http://EXAMPLE.org", "example.org"},
+	}
+	for _, tt := range tests {
+		if got := domainName(tt.in); got != tt.want { t.Errorf("domainName(%q) = %q, want %q", tt.in, got, tt.want) }
+	}
+}
+
+// whoisStub answers each query with reply(domain).
+func whoisStub(t *testing.T, reply func(domain string) string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil { return }
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			conn.Write([]byte(reply(strings.TrimSpace(line))))
+			conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestDomainStubs(t *testing.T) {
+	rdap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expiry := map[string]string{"/domain/example.com": "2031-01-02T03:04:05Z", "/domain/expired.com": "2020-01-01T00:00:00Z"}[r.URL.Path]
+		if expiry == "" { http.NotFound(w, r); return }
+		w.Write([]byte(`{"events":[{"eventAction":"registration","eventDate":"2001-01-01T00:00:00Z"},{"eventAction":"expiration","eventDate":"` + expiry + `"}],
+			"entities":[{"roles":["registrant"],"vcardArray":["vcard",[["fn",{},"text","Someone"]]]},{"roles":["registrar"],"vcardArray":["vcard",[["fn",{},"text","Example Registrar, Inc."]]]}]}`))
+	}))
+	t.Cleanup(rdap.Close)
+	registry := whoisStub(t, func(string) string {
+		return "% Registry WHOIS\r\nDomain Name: FALLBACK.TEST\r\nRegistrar: Stub Registrar\r\nRegistry Expiry Date: 2030-05-01T00:00:00Z\r\n"
+	})
+	iana := whoisStub(t, func(domain string) string {
+		if strings.HasPrefix(domain, "fallback.") { return "domain: TEST\nrefer: " + registry + "\n" }
+		return "% No referral\n"
+	})
+	prevRDAP, prevWHOIS := RDAPBaseURL, WHOISServer
+	RDAPBaseURL, WHOISServer = rdap.URL+"/", iana
+	t.Cleanup(func() { RDAPBaseURL, WHOISServer = prevRDAP, prevWHOIS })
+
+	tests := []struct{ url, status, err, registrar, expiry string }{
+		{"example.com", "UP", "", "Example Registrar, Inc.", "2031-01-02T03:04:05Z"},
+		{"https://Fallback.TEST/login", "UP", "", "Stub Registrar", "2030-05-01T00:00:00Z"},
+		{"expired.com", "DOWN", "domain registration expired 2020-01-01T00:00:00Z", "Example Registrar, Inc.", "2020-01-01T00:00:00Z"},
+		{"unknown.test", "DOWN", "rdap: HTTP 404 Not Found; whois: no expiry date in WHOIS response", "", "0001-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		site, res := checkDomain(context.Background(), models.Site{Type: "domain", URL: tt.url, Timeout: 2})
+		if res.Status != tt.status || res.Error != tt.err { t.Errorf("%s: got %s %q, want %s %q", tt.url, res.Status, res.Error, tt.status, tt.err) }
+		if site.Registrar != tt.registrar || site.DomainExpiry.Format(time.RFC3339) != tt.expiry { t.Errorf("%s: registrar %q expiry %v", tt.url, site.Registrar, site.DomainExpiry) }
+	}
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"fmt"
+	"go-upkeep/internal/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- GROUPS ---
+// A group's status is derived from its members' live statuses by its rule;
+// nothing is dialed. Members count as down while they are DOWN, SSL EXP or
+// UNREACHABLE, so DEGRADED and FLAP members still hold a group up.
+
+// GroupRules are the supported aggregation rules.
+var GroupRules = []string{"all", "any", "atleast", "weighted"}
+
+func checkGroup(site models.Site) (models.Site, CheckResult) {
+	var up, total, upWeight, totalWeight, pending int
+	var down []string
+	Mutex.RLock()
+	for _, m := range site.Members {
+		member, ok := LiveState[m.ID]
+		if !ok || m.ID == site.ID { continue } // Deleted members no longer count
+		w := max(m.Weight, 1)
+		total++; totalWeight += w
+		if member.Status == "PENDING" { pending++ }
+		if blocksDependents(member.Status) { down = append(down, member.Name+" "+member.Status); continue }
+		up++; upWeight += w
+	}
+	Mutex.RUnlock()
+
+	res := CheckResult{Status: "UP"}
+	var ok bool
+	var need string
+	switch site.Rule() {
+	case "any": ok, need = up > 0, "any"
+	case "atleast": ok, need = up >= max(site.GroupMin, 1), fmt.Sprintf("at least %d", max(site.GroupMin, 1))
+	case "weighted":
+		pct := site.GroupMin; if pct <= 0 { pct = 50 }
+		ok, need = totalWeight > 0 && upWeight*100 >= pct*totalWeight, fmt.Sprintf("%d%% of weight", pct)
+	default: ok, need = up == total, "all"
+	}
+	switch {
+	case total == 0: res.Status = "DOWN"; res.Error = "group has no members"
+	case pending == total && site.Status == "PENDING": res.Status = "PENDING" // Wait for the members' first checks
+	case !ok:
+		res.Status = "DOWN"
+		res.Error = fmt.Sprintf("%d of %d members up, need %s", up, total, need)
+		if site.Rule() == "weighted" { res.Error = fmt.Sprintf("weight %d of %d up, need %s", upWeight, totalWeight, need) }
+		res.Error += ": " + strings.Join(down, ", ")
+	}
+	site.LastCheck = time.Now()
+	return site, res
+}
+
+// groupsOf returns the IDs of the groups the site is a member of.
+func groupsOf(id int, mutedOnly bool) []int {
+	Mutex.RLock(); defer Mutex.RUnlock()
+	var groups []int
+	for _, gid := range memberOf[id] {
+		if !mutedOnly || LiveState[gid].MuteMembers { groups = append(groups, gid) }
+	}
+	return groups
+}
+
+// indexMembers moves a group's entries in the member index from old to cur. Caller holds Mutex.
+func indexMembers(old, cur models.Site) {
+	for _, m := range old.Members {
+		memberOf[m.ID] = slices.DeleteFunc(memberOf[m.ID], func(gid int) bool { return gid == old.ID })
+		if len(memberOf[m.ID]) == 0 { delete(memberOf, m.ID) }
+	}
+	if cur.Type != "group" { return }
+	for _, m := range cur.Members {
+		if m.ID != cur.ID && !slices.Contains(memberOf[m.ID], cur.ID) { memberOf[m.ID] = append(memberOf[m.ID], cur.ID) }
+	}
+}
+
+// recheckGroups re-evaluates the groups containing a site once its status changes.
+func recheckGroups(id int) {
+	for _, gid := range groupsOf(id, false) { sched.runSoon(gid) }
+}
@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"encoding/json"
+	"go-upkeep/internal/models"
+	"go-upkeep/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func members(ids ...int) []models.GroupMember {
+	var ms []models.GroupMember
+	for _, id := range ids { ms = append(ms, models.GroupMember{ID: id}) }
+	return ms
+}
+
+func TestCheckGroupRules(t *testing.T) {
+	setLive(t,
+		models.Site{ID: 1, Name: "a", Status: "UP"},
+		models.Site{ID: 2, Name: "b", Status: "DOWN"},
+		models.Site{ID: 3, Name: "c", Status: "DEGRADED"}, // Slow but serving
+		models.Site{ID: 4, Name: "d", Status: "UNREACHABLE"},
+		models.Site{ID: 5, Name: "e", Status: "FLAP"},
+		models.Site{ID: 6, Name: "f", Status: "PENDING"},
+	)
+	weighted := []models.GroupMember{{ID: 1, Weight: 3}, {ID: 2, Weight: 1}}
+	tests := []struct {
+		name  string
+		group models.Site
+		want  string
+		err   string
+	}{
+		{"all up", models.Site{Members: members(1, 3, 5)}, "UP", ""},
+		{"all with a down member", models.Site{Members: members(1, 2)}, "DOWN", "1 of 2 members up, need all: b DOWN"},
+		{"unreachable counts as down", models.Site{Members: members(1, 4)}, "DOWN", "d UNREACHABLE"},
+		{"any", models.Site{GroupRule: "any", Members: members(2, 3)}, "UP", ""},
+		{"any all down", models.Site{GroupRule: "any", Members: members(2, 4)}, "DOWN", "need any"},
+		{"atleast met", models.Site{GroupRule: "atleast", GroupMin: 2, Members: members(1, 2, 3)}, "UP", ""},
+		{"atleast missed", models.Site{GroupRule: "atleast", GroupMin: 2, Members: members(1, 2, 4)}, "DOWN", "need at least 2"},
+		{"weighted default half", models.Site{GroupRule: "weighted", Members: weighted}, "UP", ""},
+		{"weighted missed", models.Site{GroupRule: "weighted", GroupMin: 80, Members: weighted}, "DOWN", "weight 3 of 4 up, need 80% of weight"},
+		{"deleted and self members ignored", models.Site{ID: 7, Members: members(1, 7, 99)}, "UP", ""},
+		{"no members", models.Site{Members: members(99)}, "DOWN", "group has no members"},
+		{"waits for first checks", models.Site{Status: "PENDING", Members: members(6)}, "PENDING", ""},
+		{"pending member once running", models.Site{Status: "UP", Members: members(6)}, "UP", ""},
+	}
+	for _, tt := range tests {
+		_, res := checkGroup(tt.group)
+		if res.Status != tt.want || !strings.Contains(res.Error, tt.err) { t.Errorf("%s: %s %q, want %s containing %q", tt.name, res.Status, res.Error, tt.want, tt.err) }
+	}
+}
+
+func TestMemberIndex(t *testing.T) {
+	setLive(t,
+		models.Site{ID: 1, Type: "http"},
+		models.Site{ID: 10, Type: "group", Members: members(1, 2)},
+		models.Site{ID: 11, Type: "group", MuteMembers: true, Members: members(1)},
+	)
+	sorted := func(ids []int) []int { slices.Sort(ids); return ids }
+	if got := sorted(groupsOf(1, false)); !slices.Equal(got, []int{10, 11}) { t.Errorf("groupsOf(1) = %v", got) }
+	if got := groupsOf(1, true); !slices.Equal(got, []int{11}) { t.Errorf("muting groups of 1 = %v", got) }
+
+	UpdateSiteConfig(models.Site{ID: 10, Type: "group", Members: members(2, 3)})
+	if got := groupsOf(1, false); !slices.Equal(got, []int{11}) { t.Errorf("after removing member: groupsOf(1) = %v", got) }
+	if got := groupsOf(3, false); !slices.Equal(got, []int{10}) { t.Errorf("after adding member: groupsOf(3) = %v", got) }
+
+	UpdateSiteConfig(models.Site{ID: 11, Type: "http"}) // No longer a group
+	RemoveSite(10)
+	if len(memberOf) != 0 { t.Errorf("index not emptied: %v", memberOf) }
+}
+
+// alertStore installs a SQLite store whose only alert channel is a webhook, and
+// returns the titles it receives.
+func alertStore(t *testing.T) chan string {
+	store.SecretKey = "test"
+	titles := make(chan string, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Title, Message string }
+		json.NewDecoder(r.Body).Decode(&body); titles <- body.Title + ": " + body.Message
+	}))
+	t.Cleanup(srv.Close)
+	s := &store.SQLiteStore{DBPath: filepath.Join(t.TempDir(), "upkeep.db")}
+	if err := s.Init(); err != nil { t.Fatal(err) }
+	s.AddAlert("hook", "webhook", map[string]string{"url": srv.URL})
+	prev := store.Get(); store.SetGlobal(s); t.Cleanup(func() { store.SetGlobal(prev) })
+	return titles
+}
+
+func TestMutedMembersAlertThroughGroup(t *testing.T) {
+	titles := alertStore(t)
+	setLive(t,
+		models.Site{ID: 1, Name: "muted", Status: "UP", AlertID: 1},
+		models.Site{ID: 2, Name: "loud", Status: "UP", AlertID: 1},
+		models.Site{ID: 10, Name: "grp", Type: "group", Status: "UP", AlertID: 1, MuteMembers: true, Members: members(1)},
+		models.Site{ID: 11, Name: "quiet", Type: "group", Status: "UP", AlertID: 1, Members: members(2)},
+	)
+	handleStatusChange(LiveState[1], "DOWN", 500, 0)
+	handleStatusChange(LiveState[2], "DOWN", 500, 0)
+	var got []string
+	for wait := 2 * time.Second; ; wait = 200 * time.Millisecond {
+		select {
+		case s := <-titles: got = append(got, s); continue
+		case <-time.After(wait):
+		}
+		break
+	}
+	if len(got) != 1 || !strings.Contains(got[0], "'loud' is DOWN") { t.Errorf("alerts %q, want only the unmuted member's", got) }
+	if LiveState[1].Status != "DOWN" { t.Errorf("muted member status %s, want DOWN", LiveState[1].Status) }
+
+	group, res := checkGroup(LiveState[10]); group.LastError = res.Error
+	handleStatusChange(group, res.Status, 0, 0)
+	select {
+	case s := <-titles: if !strings.Contains(s, "'grp' is DOWN") || !strings.Contains(s, "muted DOWN") { t.Errorf("group alert %q", s) }
+	case <-time.After(2 * time.Second): t.Error("muting group sent no alert")
+	}
+}
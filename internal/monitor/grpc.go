@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// --- gRPC HEALTH ---
+// A unary call to grpc.health.v1.Health/Check over HTTP/2, framed and encoded
+// by hand: the request and response messages are a single field each.
+// Targets are grpc://host:port (plaintext) or grpcs://host:port (TLS).
+
+const grpcHealthPath = "/grpc.health.v1.Health/Check"
+
+// grpcHealthStatus names HealthCheckResponse.ServingStatus values.
+var grpcHealthStatus = map[uint64]string{0: "UNKNOWN", 1: "SERVING", 2: "NOT_SERVING", 3: "SERVICE_UNKNOWN"}
+
+var grpcTransport = sync.OnceValue(func() *http.Transport {
+	t := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, IdleConnTimeout: 90 * time.Second}
+	t.Protocols = new(http.Protocols)
+	t.Protocols.SetHTTP2(true); t.Protocols.SetUnencryptedHTTP2(true)
+	return t
+})
+
+func checkGRPC(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(site)); defer cancel()
+	start := time.Now()
+	status, state, err := grpcHealthCheck(ctx, site.URL, site.Service)
+	res := CheckResult{Status: "UP", Latency: time.Since(start)}
+	switch {
+	case err != nil: res.Status = "DOWN"; res.Error = err.Error()
+	case status != "SERVING": res.Status = "DOWN"; res.Error = "health status " + status
+	}
+	site.HasSSL, site.CertExpiry = false, time.Time{}
+	if state != nil { trackCert(&site, &res, state) }
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+func grpcHealthCheck(ctx context.Context, target, service string) (string, *tls.ConnectionState, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" { u, err = url.Parse("grpc://" + target) } // Bare host:port
+	if err != nil { return "", nil, err }
+	scheme := "http"
+	switch u.Scheme {
+	case "grpc":
+	case "grpcs": scheme = "https"
+	default: return "", nil, fmt.Errorf("unsupported scheme %q, use grpc:// or grpcs://", u.Scheme)
+	}
+
+	var msg []byte
+	if service != "" { msg = append(protoTag(1, 2), protoBytes(service)...) }
+	req, err := http.NewRequestWithContext(ctx, "POST", scheme+"://"+u.Host+grpcHealthPath, bytes.NewReader(grpcFrame(msg)))
+	if err != nil { return "", nil, err }
+	req.Header.Set("Content-Type", "application/grpc"); req.Header.Set("TE", "trailers")
+	resp, err := (&http.Client{Transport: grpcTransport()}).Do(req)
+	if err != nil { return "", nil, err }
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil { return "", resp.TLS, err }
+
+	// Errors arrive as trailers, or as headers in a trailers-only response
+	code, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if code == "" { code, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message") }
+	if resp.StatusCode != http.StatusOK { return "", resp.TLS, fmt.Errorf("HTTP %s", resp.Status) }
+	if code != "" && code != "0" {
+		if message, err := url.PathUnescape(message); err == nil && message != "" { return "", resp.TLS, fmt.Errorf("grpc status %s: %s", code, message) }
+		return "", resp.TLS, fmt.Errorf("grpc status %s", code)
+	}
+	status, err := parseHealthResponse(body)
+	return status, resp.TLS, err
+}
+
+// grpcFrame prefixes a message with the uncompressed flag and its length.
+func grpcFrame(msg []byte) []byte {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+func parseHealthResponse(body []byte) (string, error) {
+	if len(body) < 5 { return "", errors.New("empty health response") }
+	if body[0] != 0 { return "", errors.New("compressed health response not supported") }
+	n := binary.BigEndian.Uint32(body[1:5])
+	if uint32(len(body)-5) < n { return "", errors.New("truncated health response") }
+	msg := body[5 : 5+n]
+	status := uint64(0) // Absent field = UNKNOWN
+	for len(msg) > 0 {
+		tag, k := binary.Uvarint(msg)
+		if k <= 0 { return "", errors.New("malformed health response") }
+		msg = msg[k:]
+		field, wire := tag>>3, tag&7
+		switch wire {
+		case 0:
+			v, k := binary.Uvarint(msg)
+			if k <= 0 { return "", errors.New("malformed health response") }
+			if field == 1 { status = v }
+			msg = msg[k:]
+		case 2:
+			l, k := binary.Uvarint(msg)
+			if k <= 0 || uint64(len(msg)-k) < l { return "", errors.New("malformed health response") }
+			msg = msg[k+int(l):]
+		default:
+			return "", fmt.Errorf("unexpected wire type %d in health response", wire)
+		}
+	}
+	if name, ok := grpcHealthStatus[status]; ok { return name, nil }
+	return fmt.Sprintf("STATUS_%d", status), nil
+}
+
+func protoTag(field, wire uint64) []byte { return binary.AppendUvarint(nil, field<<3|wire) }
+
+func protoBytes(s string) []byte { return append(binary.AppendUvarint(nil, uint64(len(s))), s...) }
+
+// trackCert records the peer certificate's expiry and flags expired certificates.
+func trackCert(site *models.Site, res *CheckResult, state *tls.ConnectionState) {
+	if !site.CheckSSL || len(state.PeerCertificates) == 0 { return }
+	cert := state.PeerCertificates[0]
+	site.HasSSL = true; site.CertExpiry = cert.NotAfter
+	if time.Now().After(cert.NotAfter) { res.Status = "SSL EXP"; res.Error = "certificate expired " + cert.NotAfter.Format(time.RFC3339) }
+}
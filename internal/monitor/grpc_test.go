@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"go-upkeep/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseHealthResponse(t *testing.T) {
+	msg := func(b ...byte) []byte { return grpcFrame(b) }
+	tests := []struct {
+		name string
+		body []byte
+		want string
+		err  string
+	}{
+		{"serving", msg(0x08, 0x01), "SERVING", ""},
+		{"not serving", msg(0x08, 0x02), "NOT_SERVING", ""},
+		{"absent field", msg(), "UNKNOWN", ""},
+		{"unknown value", msg(0x08, 0x09), "STATUS_9", ""},
+		{"unknown fields skipped", msg(0x12, 0x02, 'h', 'i', 0x18, 0x05, 0x08, 0x01), "SERVING", ""},
+		{"multi-byte varint", msg(0x08, 0x81, 0x01), "STATUS_129", ""},
+		{"empty", nil, "", "empty"},
+		{"compressed", append([]byte{1}, msg(0x08, 0x01)[1:]...), "", "compressed"},
+		{"truncated", msg(0x08, 0x01)[:6], "", "truncated"},
+		{"bad length", msg(0x12, 0x05, 'h'), "", "malformed"},
+		{"fixed64", msg(0x09, 1, 2, 3, 4, 5, 6, 7, 8), "", "wire type 1"},
+	}
+	for _, tt := range tests {
+		got, err := parseHealthResponse(tt.body)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) { t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err) }
+			continue
+		}
+		if err != nil || got != tt.want { t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want) }
+	}
+}
+
+// healthServer answers Health/Check over cleartext HTTP/2: "" is serving, "db"
+// is not, and any other service is unknown.
+func healthServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 { http.Error(w, "HTTP/2 required", http.StatusHTTPVersionNotSupported); return }
+		if r.URL.Path != grpcHealthPath || r.Header.Get("Content-Type") != "application/grpc" { http.NotFound(w, r); return }
+		body, _ := io.ReadAll(r.Body)
+		service := ""
+		if len(body) > 7 { service = string(body[7:]) } // Frame header, tag, length
+		w.Header().Set("Content-Type", "application/grpc")
+		switch service {
+		case "":
+			w.Header().Set("Trailer", "Grpc-Status"); w.Write(grpcFrame([]byte{0x08, 0x01})); w.Header().Set("Grpc-Status", "0")
+		case "db":
+			w.Header().Set("Trailer", "Grpc-Status"); w.Write(grpcFrame([]byte{0x08, 0x02})); w.Header().Set("Grpc-Status", "0")
+		default:
+			w.Header().Set("Grpc-Status", "5"); w.Header().Set("Grpc-Message", "unknown%20service") // Trailers-only
+		}
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true); srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGRPCHealthLoopback(t *testing.T) {
+	srv := healthServer(t)
+	target := "grpc://" + strings.TrimPrefix(srv.URL, "http://")
+	tests := []struct{ service, status, err string }{
+		{"", "UP", ""},
+		{"db", "DOWN", "health status NOT_SERVING"},
+		{"cache", "DOWN", "grpc status 5: unknown service"},
+	}
+	for _, tt := range tests {
+		_, res := checkGRPC(context.Background(), models.Site{Type: "grpc", URL: target, Service: tt.service})
+		if res.Status != tt.status || res.Error != tt.err { t.Errorf("service %q: got %s %q, want %s %q", tt.service, res.Status, res.Error, tt.status, tt.err) }
+	}
+}
+
+func TestGRPCFrame(t *testing.T) {
+	f := grpcFrame([]byte("abc"))
+	if f[0] != 0 || binary.BigEndian.Uint32(f[1:5]) != 3 || string(f[5:]) != "abc" { t.Errorf("grpcFrame = %v", f) }
+}
@@ -1,12 +1,14 @@
 package monitor
 
 import (
-	"crypto/tls"
+	"context"
+	"errors"
 	"fmt"
 	"go-upkeep/internal/alert"
 	"go-upkeep/internal/models"
 	"go-upkeep/internal/store"
 	"net/http"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -33,6 +35,8 @@ func GetLogs() []string {
 var (
 	LiveState = make(map[int]models.Site)
 	Mutex     sync.RWMutex
+	tokens    = make(map[string]int) // Push token -> site ID. Guarded by Mutex
+	memberOf  = make(map[int][]int)  // Site ID -> groups listing it as a member. Guarded by Mutex
 	
 	// Global Switch for HA
 	isActive     = true
@@ -56,119 +60,354 @@ func IsEngineActive() bool {
 	return isActive
 }
 
-func RecordHeartbeat(token string) bool {
+// PublicURL is the base address push clients use. The HTTP server sets it on start.
+var PublicURL string
+
+// PushURL returns the address a push monitor with this token reports to.
+func PushURL(token string) string { return PublicURL + "/api/push/" + token }
+
+// Push is one report received on /api/push.
+type Push struct {
+	Start    bool // A run began; the finishing push follows
+	Down     bool
+	Message  string
+	Duration time.Duration // Runtime reported by the job, shown as latency
+}
+
+// RecordHeartbeat applies a push to the site owning token. A push reporting
+// down, or finishing later than MaxRuntime, is confirmed immediately rather
+// than waiting out retries.
+func RecordHeartbeat(token string, p Push) bool {
 	if !IsEngineActive() { return false } // Only Leader accepts Push
 	
-	Mutex.Lock(); defer Mutex.Unlock()
-	var targetID int = -1
-	for id, s := range LiveState {
-		if s.Type == "push" && s.Token == token { targetID = id; break }
-	}
-	if targetID == -1 { return false }
+	Mutex.RLock(); targetID, ok := tokens[token]; Mutex.RUnlock()
+	if !ok || token == "" { return false }
+	unlock, _ := lockSite(context.Background(), targetID); defer unlock()
 
-	site := LiveState[targetID]
-	site.LastCheck = time.Now()
-	wasDown := site.Status == "DOWN"
-	site.Status = "UP"; site.FailureCount = 0; site.Latency = 0 
-	LiveState[targetID] = site
-	
-	if wasDown {
-		AddLog(fmt.Sprintf("Push Monitor '%s' recovered", site.Name))
-		triggerAlert(site.AlertID, "✅ RECOVERY", fmt.Sprintf("Push Monitor '%s' is receiving heartbeats.", site.Name))
+	Mutex.Lock()
+	site, ok := LiveState[targetID]
+	if !ok || site.Token != token { Mutex.Unlock(); return false } // Deleted or re-keyed while waiting
+	now := time.Now()
+	site.LastCheck = now; site.LastHeartbeat = now
+	if p.Start {
+		site.JobStarted = now
+	} else {
+		if !site.JobStarted.IsZero() {
+			runtime := now.Sub(site.JobStarted)
+			if p.Duration == 0 { p.Duration = runtime }
+			if limit := time.Duration(site.MaxRuntime) * time.Second; limit > 0 && runtime > limit {
+				overrun := fmt.Sprintf("ran for %s, longer than max runtime %s", runtime.Round(time.Second), limit)
+				if p.Message != "" { overrun += ": " + p.Message }
+				p.Down, p.Message = true, overrun
+			}
+		}
+		site.JobStarted = time.Time{}
+		site.LastMessage = p.Message; site.PushFailed = p.Down; site.Latency = p.Duration
 	}
+	LiveState[targetID] = site
+	Mutex.Unlock()
+
+	if p.Down { site.FailureCount = max(site.FailureCount, site.MaxRetries) }
+	res := checkPush(site)
+	handleStatusChange(site, res.Status, res.StatusCode, site.Latency)
 	return true
 }
 
+// resyncInterval is how often the engine reconciles LiveState with the store in
+// case a change event was missed.
+const resyncInterval = time.Minute
+
+// savedStates holds state restored from the store until each site is first loaded. Guarded by Mutex.
+var savedStates map[int]models.SiteState
+
+// lastSaved is the state most recently written per site, so unchanged checks skip the write. Guarded by Mutex.
+var lastSaved = make(map[int]models.SiteState)
+
 func StartEngine() {
+	sched.run()
+	events, _ := store.Subscribe()
 	go func() {
+		for store.Get() == nil { time.Sleep(1 * time.Second) }
+		states := store.Get().GetSiteStates()
+		Mutex.Lock(); savedStates = states; for id, st := range states { lastSaved[id] = st }; Mutex.Unlock()
+		syncSites()
+		ticker := time.NewTicker(resyncInterval)
 		for {
-			s_instance := store.Get()
-			if s_instance == nil { time.Sleep(1 * time.Second); continue }
-			
-			sites := s_instance.GetSites()
-			for _, s := range sites {
-				Mutex.RLock(); _, exists := LiveState[s.ID]; Mutex.RUnlock()
-				if !exists {
-					Mutex.Lock()
-					s.Status = "PENDING"
-					if s.Type == "push" { s.LastCheck = time.Now() }
-					LiveState[s.ID] = s
-					Mutex.Unlock()
-					go monitorRoutine(s.ID)
-				}
+			select {
+			case e := <-events: applyEvent(e)
+			case <-ticker.C: syncSites()
 			}
-			time.Sleep(5 * time.Second)
 		}
 	}()
 }
 
-func UpdateSiteConfig(id int, name, url, sType string, interval, alertID int, checkSSL bool, threshold, retries int) {
+func applyEvent(e store.Event) {
+	switch e.Kind {
+	case store.SiteAdded, store.SiteUpdated:
+		if st, ok := store.Get().GetSite(e.ID); ok { upsertSite(st) } else { RemoveSite(e.ID) }
+	case store.SiteDeleted:
+		RemoveSite(e.ID)
+	case store.Reloaded:
+		syncSites()
+	}
+}
+
+// syncSites reconciles LiveState with the store: new sites are scheduled, changed
+// ones reconfigured and missing ones removed.
+func syncSites() {
+	sites := store.Get().GetSites()
+	seen := make(map[int]bool)
+	for _, st := range sites {
+		seen[st.ID] = true
+		Mutex.RLock(); live, exists := LiveState[st.ID]; Mutex.RUnlock()
+		if !exists || !reflect.DeepEqual(withRuntime(st, live), live) { upsertSite(st) }
+	}
+	Mutex.RLock()
+	var gone []int
+	for id := range LiveState { if !seen[id] { gone = append(gone, id) } }
+	Mutex.RUnlock()
+	for _, id := range gone { RemoveSite(id) }
+	pruneTransports(sites)
+}
+
+// upsertSite starts monitoring a new site or applies new configuration to a known one.
+func upsertSite(st models.Site) {
+	Mutex.Lock()
+	if _, exists := LiveState[st.ID]; exists { Mutex.Unlock(); UpdateSiteConfig(st); return }
+	st.Status = "PENDING"
+	if saved, ok := savedStates[st.ID]; ok {
+		// Resume where the previous run left off so missed heartbeats and sent warnings carry over
+		st.Status = saved.Status; st.FailureCount = saved.FailureCount
+		st.LastCheck = saved.LastCheck; st.LastHeartbeat = saved.LastHeartbeat; st.SentSSLWarning = saved.SentSSLWarning
+		st.LastMessage = saved.LastMessage; st.PushFailed = saved.PushFailed; st.JobStarted = saved.JobStarted
+		delete(savedStates, st.ID)
+	}
+	if st.Type == "push" && st.LastHeartbeat.IsZero() { st.LastHeartbeat = time.Now() }
+	LiveState[st.ID] = st
+	indexToken(models.Site{}, st); indexMembers(models.Site{}, st)
+	Mutex.Unlock()
+	sched.add(st.ID, time.Duration(st.Interval)*time.Second)
+}
+
+// UpdateSiteConfig applies edited configuration to a live site, keeping its
+// runtime state unless it now probes something else.
+func UpdateSiteConfig(cfg models.Site) {
 	Mutex.Lock(); defer Mutex.Unlock()
-	if s, ok := LiveState[id]; ok {
-		s.Name = name; s.URL = url; s.Type = sType; s.Interval = interval
-		s.AlertID = alertID; s.CheckSSL = checkSSL; s.ExpiryThreshold = threshold; s.MaxRetries = retries
-		LiveState[id] = s
+	s, ok := LiveState[cfg.ID]
+	if !ok { return }
+	if !sameTarget(cfg, s) { flapMutex.Lock(); delete(flapHistory, cfg.ID); flapMutex.Unlock() }
+	// Renames and echoes of the engine's own writes must not cancel a check in flight
+	if probeChanged(cfg, s) { sched.runSoon(cfg.ID) }
+	LiveState[cfg.ID] = withRuntime(cfg, s); indexToken(s, cfg); indexMembers(s, cfg)
+}
+
+// probeChanged reports whether cfg probes differently, or on a different schedule, than live.
+func probeChanged(cfg, live models.Site) bool {
+	a, b := withRuntime(cfg, live), live
+	for _, s := range []*models.Site{&a, &b} {
+		// Settings that only affect naming, alerting or how results are judged
+		s.Name, s.Token, s.AlertID, s.ExpiryThreshold, s.Tags, s.ParentIDs, s.MuteMembers = "", "", 0, 0, nil, nil, false
+		s.LatencyWarn, s.LatencyCrit, s.DegradedAfter = 0, 0, 0
 	}
+	return !reflect.DeepEqual(a, b)
 }
 
-func RemoveSite(id int) { Mutex.Lock(); delete(LiveState, id); Mutex.Unlock() }
+// sameTarget reports whether cfg still probes what live did. A new type or
+// address, including an ID reused by a restored backup, starts from scratch.
+func sameTarget(cfg, live models.Site) bool {
+	return cfg.Type == live.Type && cfg.URL == live.URL && cfg.DSN == live.DSN
+}
 
-func monitorRoutine(id int) {
-	checkByID(id)
-	for {
-		// If paused, just sleep loop to keep goroutine alive but idle
-		if !IsEngineActive() {
-			time.Sleep(5 * time.Second)
-			continue
-		}
+// indexToken moves a site's entry in the token index from old to cur. Caller holds Mutex.
+func indexToken(old, cur models.Site) {
+	if old.Token != "" && tokens[old.Token] == old.ID { delete(tokens, old.Token) }
+	if cur.Type == "push" && cur.Token != "" { tokens[cur.Token] = cur.ID }
+}
 
-		Mutex.RLock(); site, exists := LiveState[id]; Mutex.RUnlock()
-		if !exists { return }
-		
-		interval := site.Interval; if interval < 5 { interval = 5 }
-		time.Sleep(time.Duration(interval) * time.Second)
-		checkByID(id)
+// withRuntime copies the engine-owned fields of live onto a freshly loaded config.
+// A site with a different target gets fresh PENDING state instead.
+func withRuntime(cfg, live models.Site) models.Site {
+	if !sameTarget(cfg, live) {
+		cfg.Status = "PENDING"
+		if cfg.Type == "push" { cfg.LastHeartbeat = time.Now() }
+		return cfg
 	}
+	cfg.FailureCount = live.FailureCount; cfg.SlowCount = live.SlowCount
+	cfg.Status = live.Status; cfg.StatusCode = live.StatusCode; cfg.Latency = live.Latency
+	cfg.CertExpiry = live.CertExpiry; cfg.HasSSL = live.HasSSL; cfg.LastError = live.LastError
+	cfg.DomainExpiry = live.DomainExpiry; cfg.Registrar = live.Registrar; cfg.Banner = live.Banner
+	cfg.ContainerState = live.ContainerState; cfg.RestartCount = live.RestartCount
+	cfg.PacketLoss = live.PacketLoss; cfg.RTTMin = live.RTTMin; cfg.RTTAvg = live.RTTAvg; cfg.RTTMax = live.RTTMax
+	cfg.LastCheck = live.LastCheck; cfg.LastHeartbeat = live.LastHeartbeat; cfg.SentSSLWarning = live.SentSSLWarning
+	cfg.LastMessage = live.LastMessage; cfg.PushFailed = live.PushFailed; cfg.JobStarted = live.JobStarted
+	// A site just switched to push gets a full interval before its first heartbeat is due
+	if cfg.Type == "push" && cfg.LastHeartbeat.IsZero() { cfg.LastHeartbeat = time.Now() }
+	return cfg
 }
 
-func checkByID(id int) {
-	if !IsEngineActive() { return }
+func RemoveSite(id int) {
+	sched.remove(id)
+	Mutex.Lock(); indexToken(LiveState[id], models.Site{}); indexMembers(LiveState[id], models.Site{}); delete(LiveState, id); delete(lastSaved, id); Mutex.Unlock()
+	flapMutex.Lock(); delete(flapHistory, id); flapMutex.Unlock()
+	siteLocks.Delete(id)
+}
+
+// CheckResult is the raw outcome of a single probe, before retries, flap
+// detection and alerting are applied.
+type CheckResult struct {
+	Status     string
+	StatusCode int
+	Latency    time.Duration
+	Error      string
+	CheckedAt  time.Time
+}
+
+var (
+	ErrSiteNotFound  = errors.New("site not found")
+	ErrEnginePassive = errors.New("engine is passive (follower)")
+	ErrCheckTimeout  = errors.New("check timed out")
+)
+
+// CheckNow probes a site immediately and feeds the result through the normal
+// status handling, after any check of the site already running. The site's
+// regular schedule is left untouched.
+func CheckNow(id int) (CheckResult, error) {
+	if !IsEngineActive() { return CheckResult{}, ErrEnginePassive }
+	Mutex.RLock(); site, ok := LiveState[id]; Mutex.RUnlock()
+	if !ok { return CheckResult{}, ErrSiteNotFound }
+	ctx, cancel := context.WithTimeout(context.Background(), checkDeadline(site)); defer cancel()
+	res, err := checkByID(ctx, id)
+	if errors.Is(err, context.DeadlineExceeded) { return res, ErrCheckTimeout }
+	return res, err
+}
+
+// checkSlack lets a probe's own timeout fire and report DOWN before the outer deadline does.
+const checkSlack = 5 * time.Second
+
+// checkDeadline bounds a whole check of site: one probe timeout, scaled for
+// checks that make several round trips.
+func checkDeadline(site models.Site) time.Duration {
+	d := checkTimeout(site)
+	switch site.Type {
+	case "domain": d *= 2 // RDAP, then the WHOIS fallback
+	case "transaction": d *= time.Duration(max(len(site.Steps), 1))
+	case "ping":
+		count := site.PacketCount; if count <= 0 { count = defaultPingCount }
+		d = time.Duration(min(count, maxPingCount)) * max(defaultPingTimeout, time.Duration(site.Timeout)*time.Second)
+	}
+	return d + checkSlack
+}
+
+// siteLocks holds a one-slot channel per site ID. Checks and heartbeats of a
+// site take it for the whole read-probe-write cycle, so a manual check or a push
+// can't be overwritten by a scheduled check that started from older state.
+var siteLocks sync.Map
+
+// lockSite waits for the site's slot, or until ctx is done.
+func lockSite(ctx context.Context, id int) (func(), error) {
+	v, _ := siteLocks.LoadOrStore(id, make(chan struct{}, 1)); slot := v.(chan struct{})
+	select {
+	case slot <- struct{}{}: return func() { <-slot }, nil
+	case <-ctx.Done(): return func() {}, ctx.Err()
+	}
+}
+
+func checkByID(ctx context.Context, id int) (CheckResult, error) {
+	if !IsEngineActive() { return CheckResult{}, ErrEnginePassive }
+	unlock, err := lockSite(ctx, id); defer unlock()
+	if err != nil { return CheckResult{}, err }
 
 	Mutex.RLock(); site, exists := LiveState[id]; Mutex.RUnlock()
-	if !exists { return }
-	if site.Type == "http" { checkHTTP(site) } else { checkPush(site) }
+	if !exists { return CheckResult{}, ErrSiteNotFound }
+
+	var res CheckResult
+	switch site.Type {
+	case "http": site, res = checkHTTP(ctx, site)
+	case "ping": site, res = checkPing(ctx, site)
+	case "postgres", "mysql", "redis": site, res = checkDatabase(ctx, site)
+	case "grpc": site, res = checkGRPC(ctx, site)
+	case "websocket": site, res = checkWebSocket(ctx, site)
+	case "transaction": site, res = checkTransaction(ctx, site)
+	case "domain": site, res = checkDomain(ctx, site)
+	case "ssh", "smtp": site, res = checkBanner(ctx, site)
+	case "udp": site, res = checkUDP(ctx, site)
+	case "docker": site, res = checkDocker(ctx, site)
+	case "group": site, res = checkGroup(site)
+	default: res = checkPush(site)
+	}
+	site.LastError = res.Error
+	res.CheckedAt = time.Now()
+	// Cancelled because the site was deleted or edited, or out of time; the result no longer applies
+	if err := ctx.Err(); err != nil { return res, err }
+	handleStatusChange(site, res.Status, res.StatusCode, res.Latency)
+	return res, nil
 }
 
-func checkPush(site models.Site) {
-	deadline := site.LastCheck.Add(time.Duration(site.Interval) * time.Second).Add(5 * time.Second)
+func checkPush(site models.Site) CheckResult {
+	if site.PushFailed {
+		res := CheckResult{Status: "DOWN", Error: "push reported down", Latency: site.Latency}
+		if site.LastMessage != "" { res.Error += ": " + site.LastMessage }
+		return res
+	}
+	deadline, err := PushDeadline(site)
+	if err != nil { return CheckResult{Status: "DOWN", Error: err.Error()} }
 	if time.Now().After(deadline) {
-		handleStatusChange(site, "DOWN", 0, 0)
-	} else {
-		if site.Status != "UP" { handleStatusChange(site, "UP", 200, 0) }
+		if !site.JobStarted.IsZero() {
+			return CheckResult{Status: "DOWN", Error: fmt.Sprintf("run started %s has not finished", site.JobStarted.Format(time.RFC3339))}
+		}
+		return CheckResult{Status: "DOWN", Error: fmt.Sprintf("no heartbeat since %s", site.LastHeartbeat.Format(time.RFC3339))}
 	}
+	return CheckResult{Status: "UP", StatusCode: 200, Latency: site.Latency}
 }
 
-func checkHTTP(site models.Site) {
-	start := time.Now()
-	client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
-	resp, err := client.Get(site.URL)
-	latency := time.Since(start)
+// defaultGrace is how late a heartbeat may arrive when no grace period is set.
+const defaultGrace = 5 * time.Second
 
-	rawStatus := "UP"; rawCode := 0; var certExpiry time.Time; hasSSL := false
+// PushDeadline returns when a push monitor becomes overdue: the next cron run
+// (or Interval) after the last heartbeat plus the grace period. While a run is in
+// progress, the finish ping is due MaxRuntime after the start.
+func PushDeadline(site models.Site) (time.Time, error) {
+	grace := defaultGrace
+	if site.GracePeriod > 0 { grace = time.Duration(site.GracePeriod) * time.Second }
+	if !site.JobStarted.IsZero() && site.MaxRuntime > 0 {
+		return site.JobStarted.Add(time.Duration(site.MaxRuntime) * time.Second), nil
+	}
+	if site.Cron == "" { return site.LastHeartbeat.Add(time.Duration(site.Interval)*time.Second + grace), nil }
+
+	schedule, err := ParseCron(site.Cron)
+	if err != nil { return time.Time{}, err }
+	loc := time.Local
+	if site.Timezone != "" {
+		if loc, err = time.LoadLocation(site.Timezone); err != nil { return time.Time{}, err }
+	}
+	// An early heartbeat within the grace period still counts for the upcoming run
+	due, err := schedule.Next(site.LastHeartbeat.Add(grace).In(loc))
+	if err != nil { return time.Time{}, err }
+	return due.Add(grace), nil
+}
 
-	if err != nil { rawStatus = "DOWN"
+func checkHTTP(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	start := time.Now()
+	client, err := clientFor(site)
+	var req *http.Request
+	if err == nil { req, err = http.NewRequestWithContext(ctx, "GET", site.URL, nil) }
+	if err == nil { setAuth(req, site) }
+	var resp *http.Response
+	if err == nil { resp, err = client.Do(req) }
+	res := CheckResult{Status: "UP", Latency: time.Since(start)}
+	site.HasSSL, site.CertExpiry = false, time.Time{}
+
+	if err != nil { res.Status = "DOWN"; res.Error = err.Error()
 	} else {
-		defer resp.Body.Close(); rawCode = resp.StatusCode
-		if resp.StatusCode >= 400 { rawStatus = "DOWN" }
-		if site.CheckSSL && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
-			hasSSL = true; cert := resp.TLS.PeerCertificates[0]; certExpiry = cert.NotAfter
-			if time.Now().After(cert.NotAfter) { rawStatus = "SSL EXP" }
+		defer resp.Body.Close(); res.StatusCode = resp.StatusCode
+		if resp.StatusCode >= 400 { res.Status = "DOWN"; res.Error = resp.Status }
+		if site.RedirectPolicy == "fail" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+			res.Status = "DOWN"; res.Error = fmt.Sprintf("redirect to %s treated as failure", resp.Header.Get("Location"))
 		}
+		if resp.TLS != nil { trackCert(&site, &res, resp.TLS) }
 	}
-	updatedSite := site
-	updatedSite.HasSSL = hasSSL; updatedSite.CertExpiry = certExpiry
-	updatedSite.Latency = latency; updatedSite.LastCheck = time.Now()
-	handleStatusChange(updatedSite, rawStatus, rawCode, latency)
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
 }
 
 func handleStatusChange(site models.Site, rawStatus string, code int, latency time.Duration) {
@@ -176,9 +415,29 @@ func handleStatusChange(site models.Site, rawStatus string, code int, latency ti
 	if !IsEngineActive() { return }
 
 	newState := site
+	// Members of a muting group only alert through the group
+	alertID := site.AlertID
+	if len(groupsOf(site.ID, true)) > 0 { alertID = 0 }
 	newState.StatusCode = code
+	flapping := trackFlapping(site, rawStatus)
+	// A push's duration is the job's runtime, not response latency
+	if rawStatus == "UP" && site.Type != "push" { rawStatus = latencyStatus(&newState, latency) }
+	failed := rawStatus != "UP" && rawStatus != "DEGRADED"
 
-	if site.Status == "UP" && rawStatus != "UP" {
+	if failed && parentBroken(site) {
+		// Failures behind a broken parent are expected; don't count or alert on them
+		newState.Status = "UNREACHABLE"; newState.FailureCount = 0
+		if site.Status != "UNREACHABLE" { AddLog(fmt.Sprintf("Monitor '%s' UNREACHABLE (parent down), alerts suppressed", site.Name)) }
+	} else if flapping {
+		// One notification when flapping starts; further alerts are held until it settles
+		newState.Status = "FLAP"; newState.FailureCount = 0
+		if site.Status != "FLAP" {
+			AddLog(fmt.Sprintf("Monitor '%s' is FLAPPING, holding alerts", site.Name))
+			triggerAlert(alertID, "🔁 FLAPPING", fmt.Sprintf("Monitor '%s' is flapping between UP and DOWN. Alerts are held until it stabilizes.", site.Name))
+		}
+	} else if rawStatus == "DEGRADED" {
+		newState.FailureCount = 0; newState.Status = "DEGRADED"
+	} else if (site.Status == "UP" || site.Status == "DEGRADED") && failed {
 		newState.FailureCount++
 		if newState.FailureCount > site.MaxRetries {
 			newState.Status = rawStatus; newState.FailureCount = site.MaxRetries + 1
@@ -192,30 +451,177 @@ func handleStatusChange(site models.Site, rawStatus string, code int, latency ti
 		newState.Status = rawStatus; newState.FailureCount = site.MaxRetries + 1 
 	}
 	
-	if site.Type == "http" && site.CheckSSL && site.HasSSL {
+	if site.CheckSSL && site.HasSSL {
 		daysLeft := int(time.Until(site.CertExpiry).Hours() / 24)
 		if daysLeft <= site.ExpiryThreshold && !site.SentSSLWarning && rawStatus != "SSL EXP" {
-			triggerAlert(site.AlertID, "SSL WARNING", fmt.Sprintf("SSL for '%s' expires in %d days", site.Name, daysLeft))
+			triggerAlert(alertID, "SSL WARNING", fmt.Sprintf("SSL for '%s' expires in %d days", site.Name, daysLeft))
+			newState.SentSSLWarning = true
+		} else if daysLeft > site.ExpiryThreshold { newState.SentSSLWarning = false }
+	}
+	if site.Type == "domain" && !site.DomainExpiry.IsZero() {
+		daysLeft := int(time.Until(site.DomainExpiry).Hours() / 24)
+		if daysLeft <= site.ExpiryThreshold && daysLeft >= 0 && !site.SentSSLWarning {
+			triggerAlert(alertID, "DOMAIN WARNING", fmt.Sprintf("Domain registration for '%s' expires in %d days", site.Name, daysLeft))
 			newState.SentSSLWarning = true
 		} else if daysLeft > site.ExpiryThreshold { newState.SentSSLWarning = false }
 	}
 
-	Mutex.Lock(); if _, ok := LiveState[site.ID]; ok { LiveState[site.ID] = newState }; Mutex.Unlock()
+	Mutex.Lock(); _, exists := LiveState[site.ID]; if exists { LiveState[site.ID] = newState }; Mutex.Unlock()
+	if exists { persistState(newState) }
 
-	isBroken := func(s string) bool { return s == "DOWN" || s == "SSL EXP" }
 	if !isBroken(site.Status) && isBroken(newState.Status) && newState.Status != "PENDING" {
 		msg := fmt.Sprintf("Monitor '%s' is DOWN (%s)", site.Name, rawStatus)
-		if site.Type == "push" { msg = fmt.Sprintf("Push Monitor '%s' missed heartbeat.", site.Name) }
-		triggerAlert(site.AlertID, "🚨 ALERT", msg)
+		if site.LastError != "" { msg += ": " + site.LastError }
+		if site.Type == "push" {
+			msg = fmt.Sprintf("Push Monitor '%s' missed heartbeat.", site.Name)
+			if site.PushFailed { msg = fmt.Sprintf("Push Monitor '%s' reported DOWN.", site.Name) }
+		}
+		triggerAlert(alertID, "🚨 ALERT", withPushMessage(site, msg))
 	}
 	if isBroken(site.Status) && newState.Status == "UP" {
-		triggerAlert(site.AlertID, "✅ RECOVERY", fmt.Sprintf("Monitor '%s' is UP", site.Name))
+		triggerAlert(alertID, "✅ RECOVERY", withPushMessage(site, fmt.Sprintf("Monitor '%s' is UP", site.Name)))
+	}
+	if site.Status != "DEGRADED" && newState.Status == "DEGRADED" {
+		ms := int(latency / time.Millisecond); level, limit := "warning", site.LatencyWarn
+		if site.LatencyCrit > 0 && ms >= site.LatencyCrit { level, limit = "critical", site.LatencyCrit }
+		reason := fmt.Sprintf("latency %dms exceeds %s threshold of %dms", ms, level, limit)
+		if lossDegraded(site) { reason = fmt.Sprintf("packet loss %.0f%% exceeds threshold of %d%%", site.PacketLoss, site.LossWarn) }
+		AddLog(fmt.Sprintf("Monitor '%s' DEGRADED (%s)", site.Name, reason))
+		if isBroken(site.Status) {
+			// Resolves the DOWN alert; the later return to UP sends the usual notice
+			triggerAlert(alertID, "✅ RECOVERY", fmt.Sprintf("Monitor '%s' recovered (degraded): %s", site.Name, reason))
+		} else {
+			triggerAlert(alertID, "⚠️ DEGRADED", fmt.Sprintf("Monitor '%s' is DEGRADED: %s", site.Name, reason))
+		}
+	}
+	if site.Status == "DEGRADED" && newState.Status == "UP" {
+		triggerAlert(alertID, "✅ RECOVERY", fmt.Sprintf("Monitor '%s' is back to normal (%dms)", site.Name, int(latency/time.Millisecond)))
+	}
+	if site.Status == "FLAP" && newState.Status == "UP" {
+		AddLog(fmt.Sprintf("Monitor '%s' stopped flapping", site.Name))
+		triggerAlert(alertID, "✅ STABLE", fmt.Sprintf("Monitor '%s' stopped flapping and is UP", site.Name))
+	}
+	if blocksDependents(site.Status) != blocksDependents(newState.Status) { recheckChildren(site.ID); recheckGroups(site.ID) }
+}
+
+// lossDegraded reports whether a ping site is DEGRADED by packet loss rather than latency.
+func lossDegraded(site models.Site) bool {
+	return site.Type == "ping" && site.LossWarn > 0 && site.PacketLoss >= float64(site.LossWarn)
+}
+
+// withPushMessage appends the message sent with the last push to alert text.
+func withPushMessage(site models.Site, msg string) string {
+	if site.Type != "push" || site.LastMessage == "" { return msg }
+	return msg + "\nMessage: " + site.LastMessage
+}
+
+// persistState saves the restart-surviving part of a site's state when any of it
+// changed since the last write. LastCheck alone moves on every check and is not worth one.
+func persistState(site models.Site) {
+	s_instance := store.Get(); if s_instance == nil { return }
+	st := models.SiteState{
+		SiteID: site.ID, Status: site.Status, FailureCount: site.FailureCount,
+		LastCheck: site.LastCheck, LastHeartbeat: site.LastHeartbeat, SentSSLWarning: site.SentSSLWarning,
+		LastMessage: site.LastMessage, PushFailed: site.PushFailed, JobStarted: site.JobStarted,
+	}
+	Mutex.Lock(); prev, ok := lastSaved[site.ID]; changed := !ok || stateChanged(prev, st); if changed { lastSaved[site.ID] = st }; Mutex.Unlock()
+	if changed { s_instance.SaveSiteState(st) }
+}
+
+// stateChanged reports whether cur differs from old in anything but LastCheck.
+func stateChanged(old, cur models.SiteState) bool {
+	return old.Status != cur.Status || old.FailureCount != cur.FailureCount || old.SentSSLWarning != cur.SentSSLWarning ||
+		old.LastMessage != cur.LastMessage || old.PushFailed != cur.PushFailed ||
+		!old.LastHeartbeat.Equal(cur.LastHeartbeat) || !old.JobStarted.Equal(cur.JobStarted)
+}
+
+// latencyStatus counts consecutive slow checks on s and returns DEGRADED once the
+// warning threshold has been exceeded DegradedAfter times in a row, or right away
+// when the critical threshold is crossed.
+func latencyStatus(s *models.Site, latency time.Duration) string {
+	ms := int(latency / time.Millisecond)
+	if s.LatencyCrit > 0 && ms >= s.LatencyCrit { s.SlowCount++; return "DEGRADED" }
+	if s.LatencyWarn > 0 && ms >= s.LatencyWarn {
+		s.SlowCount++
+		if s.SlowCount >= max(s.DegradedAfter, 1) { return "DEGRADED" }
+		return "UP"
+	}
+	s.SlowCount = 0
+	return "UP"
+}
+
+// --- FLAP DETECTION ---
+// A monitor flaps when the share of state changes across its recent raw results
+// crosses flapStart; it only counts as stable again once that share falls to flapStop.
+
+const (
+	flapWindow     = 10
+	flapMinSamples = 5
+	flapStart      = 0.5
+	flapStop       = 0.2
+)
+
+var (
+	flapHistory = make(map[int][]bool)
+	flapMutex   sync.Mutex
+)
+
+// trackFlapping records a raw result and reports whether the site should be treated as flapping.
+func trackFlapping(site models.Site, rawStatus string) bool {
+	flapMutex.Lock(); defer flapMutex.Unlock()
+	h := append(flapHistory[site.ID], rawStatus == "UP")
+	if len(h) > flapWindow { h = h[len(h)-flapWindow:] }
+	flapHistory[site.ID] = h
+	// A site restored as FLAP keeps that status until there is enough history to judge it
+	if len(h) < flapMinSamples { return site.Status == "FLAP" }
+
+	changes := 0
+	for i := 1; i < len(h); i++ { if h[i] != h[i-1] { changes++ } }
+	rate := float64(changes) / float64(len(h)-1)
+	if site.Status == "FLAP" { return rate > flapStop }
+	return rate >= flapStart
+}
+
+func isBroken(status string) bool { return status == "DOWN" || status == "SSL EXP" }
+
+// blocksDependents reports whether monitors depending on a site in this status are unreachable.
+func blocksDependents(status string) bool { return isBroken(status) || status == "UNREACHABLE" }
+
+// parentBroken reports whether an ancestor of the site is actually failing.
+// UNREACHABLE parents are looked through to their own parents rather than
+// trusted, so sites can never hold each other UNREACHABLE.
+func parentBroken(site models.Site) bool {
+	Mutex.RLock(); defer Mutex.RUnlock()
+	seen := map[int]bool{site.ID: true}
+	queue := append([]int(nil), site.ParentIDs...)
+	for len(queue) > 0 {
+		id := queue[0]; queue = queue[1:]
+		if seen[id] { continue }
+		seen[id] = true
+		p, ok := LiveState[id]
+		if !ok { continue }
+		if isBroken(p.Status) { return true }
+		if p.Status == "UNREACHABLE" { queue = append(queue, p.ParentIDs...) }
+	}
+	return false
+}
+
+// recheckChildren re-evaluates the dependents of a site right away instead of
+// waiting for their next interval.
+func recheckChildren(parentID int) {
+	Mutex.RLock()
+	var children []int
+	for id, s := range LiveState {
+		for _, pid := range s.ParentIDs {
+			if pid == parentID { children = append(children, id); break }
+		}
 	}
+	Mutex.RUnlock()
+	for _, id := range children { sched.runSoon(id) }
 }
 
 func triggerAlert(alertID int, title, message string) {
 	s_instance := store.Get(); if s_instance == nil { return }
 	cfg, ok := s_instance.GetAlert(alertID); if !ok { return }
-	provider := alert.GetProvider(cfg)
-	if provider != nil { go func() { provider.Send(title, message) }() }
+	alert.Dispatch(cfg, title, message)
 }
\ No newline at end of file
@@ -0,0 +1,179 @@
+package monitor
+
+import (
+	"container/heap"
+	"go-upkeep/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+// setLive replaces the engine state (with its member index and flap history) for a test and restores it afterwards.
+func setLive(t *testing.T, sites ...models.Site) {
+	t.Helper()
+	Mutex.Lock()
+	old, oldMembers := LiveState, memberOf
+	LiveState, memberOf = make(map[int]models.Site), make(map[int][]int)
+	for _, s := range sites { LiveState[s.ID] = s; indexMembers(models.Site{}, s) }
+	Mutex.Unlock()
+	flapMutex.Lock(); oldFlaps := flapHistory; flapHistory = make(map[int][]bool); flapMutex.Unlock()
+	t.Cleanup(func() {
+		Mutex.Lock(); LiveState, memberOf = old, oldMembers; Mutex.Unlock()
+		flapMutex.Lock(); flapHistory = oldFlaps; flapMutex.Unlock()
+	})
+}
+
+func TestParentBroken(t *testing.T) {
+	setLive(t,
+		models.Site{ID: 1, Status: "DOWN"},
+		models.Site{ID: 2, Status: "UNREACHABLE", ParentIDs: []int{1}},
+		models.Site{ID: 3, Status: "UP", ParentIDs: []int{2}},
+		models.Site{ID: 4, Status: "UP", ParentIDs: []int{3}},
+		// A loop where one side is DOWN must not keep the other UNREACHABLE forever
+		models.Site{ID: 10, Status: "DOWN", ParentIDs: []int{11}},
+		models.Site{ID: 11, Status: "UNREACHABLE", ParentIDs: []int{10}},
+	)
+	tests := []struct {
+		site models.Site
+		want bool
+	}{
+		{models.Site{ID: 3, ParentIDs: []int{2}}, true}, // Through an UNREACHABLE parent to the DOWN root
+		{models.Site{ID: 4, ParentIDs: []int{3}}, false}, // UP parents are trusted
+		{models.Site{ID: 10, ParentIDs: []int{11}}, false},
+		{models.Site{ID: 11, ParentIDs: []int{10}}, true},
+		{models.Site{ID: 5, ParentIDs: []int{99}}, false},
+	}
+	for _, tt := range tests {
+		if got := parentBroken(tt.site); got != tt.want { t.Errorf("parentBroken(#%d) = %v, want %v", tt.site.ID, got, tt.want) }
+	}
+}
+
+func TestLatencyThresholdsSkipPush(t *testing.T) {
+	push := models.Site{ID: 1, Type: "push", Status: "UP", LatencyWarn: 100, LatencyCrit: 200, DegradedAfter: 1}
+	http := models.Site{ID: 2, Type: "http", Status: "UP", LatencyWarn: 100, LatencyCrit: 200, DegradedAfter: 1}
+	setLive(t, push, http)
+	handleStatusChange(push, "UP", 0, 5*time.Second)
+	handleStatusChange(http, "UP", 200, 5*time.Second)
+	if got := LiveState[1].Status; got != "UP" { t.Errorf("push with a long job: status %s, want UP", got) }
+	if got := LiveState[2].Status; got != "DEGRADED" { t.Errorf("slow http: status %s, want DEGRADED", got) }
+}
+
+func TestWithRuntimeResetsOnNewTarget(t *testing.T) {
+	live := models.Site{ID: 1, Type: "http", URL: "https://a.example", Status: "DOWN", FailureCount: 3, LastError: "timeout"}
+	kept := withRuntime(models.Site{ID: 1, Type: "http", URL: "https://a.example", Name: "Renamed"}, live)
+	if kept.Status != "DOWN" || kept.FailureCount != 3 || kept.Name != "Renamed" { t.Errorf("same target lost runtime state: %+v", kept) }
+	for _, cfg := range []models.Site{
+		{ID: 1, Type: "http", URL: "https://b.example"},
+		{ID: 1, Type: "ping", URL: "https://a.example"},
+	} {
+		got := withRuntime(cfg, live)
+		if got.Status != "PENDING" || got.FailureCount != 0 || got.LastError != "" { t.Errorf("%s %s inherited state: %+v", cfg.Type, cfg.URL, got) }
+	}
+}
+
+func TestStateChanged(t *testing.T) {
+	now := time.Now()
+	base := models.SiteState{SiteID: 1, Status: "UP", LastCheck: now, LastHeartbeat: now}
+	later := base; later.LastCheck = now.Add(time.Minute)
+	down := base; down.Status = "DOWN"
+	fails := base; fails.FailureCount = 1
+	warned := base; warned.SentSSLWarning = true
+	beat := base; beat.LastHeartbeat = now.Add(time.Minute)
+	for name, tc := range map[string]struct { cur models.SiteState; want bool }{
+		"last check only": {later, false}, "status": {down, true}, "failures": {fails, true},
+		"warning": {warned, true}, "heartbeat": {beat, true},
+	} {
+		if got := stateChanged(base, tc.cur); got != tc.want { t.Errorf("%s: got %v, want %v", name, got, tc.want) }
+	}
+}
+
+func TestCheckDeadline(t *testing.T) {
+	steps := []models.Step{{}, {}, {}}
+	for _, tc := range []struct { site models.Site; want time.Duration }{
+		{models.Site{Type: "http"}, defaultTimeout + checkSlack},
+		{models.Site{Type: "http", Timeout: 30}, 30*time.Second + checkSlack},
+		{models.Site{Type: "domain", Timeout: 10}, 20*time.Second + checkSlack},
+		{models.Site{Type: "transaction", Timeout: 10, Steps: steps}, 30*time.Second + checkSlack},
+		{models.Site{Type: "ping"}, 3*time.Second + checkSlack},
+	} {
+		if got := checkDeadline(tc.site); got != tc.want { t.Errorf("%s: got %v, want %v", tc.site.Type, got, tc.want) }
+	}
+}
+
+func TestPruneTransports(t *testing.T) {
+	a := models.Site{ID: 1, ProxyURL: "http://proxy-a:3128"}
+	b := models.Site{ID: 2, ProxyURL: "http://proxy-b:3128"}
+	for _, s := range []models.Site{a, b} {
+		if _, err := transportFor(s); err != nil { t.Fatal(err) }
+	}
+	pruneTransports([]models.Site{a})
+	transportMutex.Lock(); defer transportMutex.Unlock()
+	if _, ok := transports[keyFor(a)]; !ok { t.Error("transport still in use was pruned") }
+	if _, ok := transports[keyFor(b)]; ok { t.Error("unused transport was kept") }
+}
+
+func TestFlapping(t *testing.T) {
+	tests := []struct {
+		name, start, raw string // raw: U = UP, D = DOWN, one check per letter
+		want             []string
+	}{
+		{"alternating enters FLAP", "UP", "UDUDU", []string{"UP", "DOWN", "UP", "DOWN", "FLAP"}},
+		{"failures inside FLAP are held", "UP", "UDUDUD", []string{"UP", "DOWN", "UP", "DOWN", "FLAP", "FLAP"}},
+		{"steady failure is not flapping", "UP", "UDDDDD", []string{"UP", "DOWN", "DOWN", "DOWN", "DOWN", "DOWN"}},
+		// Leaves FLAP only once changes fall to a fifth of the window
+		{"settles back to UP", "UP", "UDUDUUUUUUUUU", []string{"UP", "DOWN", "UP", "DOWN", "FLAP", "FLAP", "FLAP", "FLAP", "FLAP", "FLAP", "FLAP", "FLAP", "UP"}},
+		{"restored FLAP waits for history", "FLAP", "UUUUU", []string{"FLAP", "FLAP", "FLAP", "FLAP", "UP"}},
+	}
+	for _, tt := range tests {
+		setLive(t, models.Site{ID: 1, Name: "api", Type: "http", Status: tt.start})
+		var got []string
+		for _, r := range tt.raw {
+			raw := "UP"; if r == 'D' { raw = "DOWN" }
+			handleStatusChange(LiveState[1], raw, 0, 0)
+			got = append(got, LiveState[1].Status)
+		}
+		if !slices.Equal(got, tt.want) { t.Errorf("%s: got %v, want %v", tt.name, got, tt.want) }
+	}
+}
+
+func TestUpdateSiteConfigResetsFlapHistory(t *testing.T) {
+	site := models.Site{ID: 1, Name: "api", Type: "http", URL: "https://a.example", Status: "FLAP"}
+	setLive(t, site)
+	for range 4 { trackFlapping(site, "DOWN") }
+	history := func() int { flapMutex.Lock(); defer flapMutex.Unlock(); return len(flapHistory[1]) }
+
+	renamed := site; renamed.Name = "api-renamed"
+	UpdateSiteConfig(renamed)
+	if history() != 4 { t.Errorf("rename dropped flap history") }
+	moved := site; moved.URL = "https://b.example"
+	UpdateSiteConfig(moved)
+	if history() != 0 || LiveState[1].Status != "PENDING" { t.Errorf("new target kept flap history (%d) or status %s", history(), LiveState[1].Status) }
+}
+
+func TestUpdateSiteConfigReschedulesOnlyProbeChanges(t *testing.T) {
+	site := models.Site{ID: 1, Name: "api", Type: "http", URL: "https://a.example", Interval: 60, Status: "UP"}
+	setLive(t, site)
+	sched.add(1, time.Minute); t.Cleanup(func() { sched.remove(1) })
+	later := time.Now().Add(time.Hour)
+	queuedAt := func() time.Time { sched.mu.Lock(); defer sched.mu.Unlock(); return sched.jobs[1].next }
+
+	tests := []struct {
+		name  string
+		edit  func(*models.Site)
+		rerun bool
+	}{
+		{"unchanged echo", func(*models.Site) {}, false},
+		{"rename", func(s *models.Site) { s.Name = "api-2"; s.AlertID = 3; s.Tags = []string{"env:prod"} }, false},
+		{"thresholds", func(s *models.Site) { s.LatencyWarn = 500 }, false},
+		{"interval", func(s *models.Site) { s.Interval = 30 }, true},
+		{"timeout", func(s *models.Site) { s.Timeout = 10 }, true},
+		{"target", func(s *models.Site) { s.URL = "https://b.example" }, true},
+	}
+	for _, tt := range tests {
+		sched.mu.Lock(); j := sched.jobs[1]; j.next = later; heap.Fix(&sched.queue, j.index); sched.mu.Unlock()
+		cfg := site; tt.edit(&cfg)
+		UpdateSiteConfig(cfg)
+		if rerun := queuedAt().Before(later); rerun != tt.rerun { t.Errorf("%s: rescheduled = %v, want %v", tt.name, rerun, tt.rerun) }
+		setLive(t, site)
+	}
+}
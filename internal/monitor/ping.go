@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// --- ICMP PING ---
+// Echo requests go out one at a time over an unprivileged ICMP datagram socket,
+// or a raw socket where that is not allowed. Loss and RTT statistics become the
+// check result; loss thresholds decide between UP, DEGRADED and DOWN.
+
+const (
+	defaultPingCount   = 3
+	defaultPingTimeout = time.Second // Per packet
+	maxPingCount       = 20
+	pingCookieLen      = 8
+)
+
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+type pingStats struct {
+	sent, received   int
+	min, avg, max    time.Duration
+}
+
+func (s pingStats) loss() float64 {
+	if s.sent == 0 { return 100 }
+	return float64(s.sent-s.received) * 100 / float64(s.sent)
+}
+
+func checkPing(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	stats, err := ping(ctx, pingHost(site.URL), site)
+	res := CheckResult{Status: "UP", Latency: stats.avg}
+	site.PacketLoss = stats.loss(); site.RTTMin, site.RTTAvg, site.RTTMax = stats.min, stats.avg, stats.max
+	site.Latency = stats.avg; site.LastCheck = time.Now()
+
+	lossCrit := float64(site.LossCrit)
+	if lossCrit <= 0 { lossCrit = 100 }
+	switch {
+	case err != nil:
+		res.Status = "DOWN"; res.Error = err.Error()
+	case site.PacketLoss >= lossCrit:
+		res.Status = "DOWN"; res.Error = fmt.Sprintf("%.0f%% packet loss (%d/%d replies)", site.PacketLoss, stats.received, stats.sent)
+	case site.LossWarn > 0 && site.PacketLoss >= float64(site.LossWarn):
+		res.Status = "DEGRADED"; res.Error = fmt.Sprintf("%.0f%% packet loss (%d/%d replies)", site.PacketLoss, stats.received, stats.sent)
+	}
+	return site, res
+}
+
+// pingHost accepts a bare host as well as a URL or host:port typed into the URL field.
+func pingHost(target string) string {
+	target = strings.TrimSpace(target)
+	if u, err := url.Parse(target); err == nil && u.Host != "" { return u.Hostname() }
+	if host, _, err := net.SplitHostPort(target); err == nil { return host }
+	return strings.Trim(target, "[]")
+}
+
+func ping(ctx context.Context, host string, site models.Site) (pingStats, error) {
+	var stats pingStats
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil { return stats, err }
+	if len(ips) == 0 { return stats, fmt.Errorf("no addresses for %s", host) }
+	ip := ips[0].IP
+	for _, a := range ips { if a.IP.To4() != nil { ip = a.IP; break } } // Prefer IPv4
+	v6 := ip.To4() == nil
+
+	conn, raw, err := listenICMP(v6)
+	if err != nil { return stats, err }
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if raw { dst = &net.IPAddr{IP: ip} }
+	count := site.PacketCount
+	if count <= 0 { count = defaultPingCount }
+	count = min(count, maxPingCount)
+	timeout := defaultPingTimeout
+	if site.Timeout > 0 { timeout = time.Duration(site.Timeout) * time.Second }
+
+	id := uint16(time.Now().UnixNano()) // Rewritten by the kernel on datagram sockets
+	cookie := make([]byte, pingCookieLen); rand.Read(cookie)
+	var total time.Duration
+	for seq := 1; seq <= count; seq++ {
+		if ctx.Err() != nil { return stats, ctx.Err() }
+		rtt, err := echo(conn, dst, v6, raw, id, uint16(seq), cookie, timeout)
+		stats.sent++
+		if err != nil {
+			if errors.Is(err, errPingTimeout) { continue }
+			if ctx.Err() != nil { return stats, ctx.Err() }
+			return stats, err
+		}
+		stats.received++; total += rtt
+		if stats.min == 0 || rtt < stats.min { stats.min = rtt }
+		if rtt > stats.max { stats.max = rtt }
+	}
+	if stats.received > 0 { stats.avg = total / time.Duration(stats.received) }
+	return stats, nil
+}
+
+var errPingTimeout = errors.New("ping timeout")
+
+// echo sends one request and waits for its reply, ignoring unrelated ICMP traffic.
+func echo(conn net.PacketConn, dst net.Addr, v6, raw bool, id, seq uint16, cookie []byte, timeout time.Duration) (time.Duration, error) {
+	reqType, replyType := byte(icmpv4EchoRequest), byte(icmpv4EchoReply)
+	if v6 { reqType, replyType = icmpv6EchoRequest, icmpv6EchoReply }
+	pkt := make([]byte, 8+len(cookie))
+	pkt[0] = reqType
+	binary.BigEndian.PutUint16(pkt[4:], id); binary.BigEndian.PutUint16(pkt[6:], seq)
+	copy(pkt[8:], cookie)
+	if !v6 { binary.BigEndian.PutUint16(pkt[2:], icmpChecksum(pkt)) } // The kernel fills in ICMPv6 checksums
+
+	start := time.Now()
+	if _, err := conn.WriteTo(pkt, dst); err != nil { return 0, err }
+	conn.SetReadDeadline(start.Add(timeout))
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() { return 0, errPingTimeout }
+			return 0, err
+		}
+		reply := buf[:n]
+		if len(reply) < 8+len(cookie) || reply[0] != replyType { continue }
+		if binary.BigEndian.Uint16(reply[6:]) != seq || string(reply[8:8+len(cookie)]) != string(cookie) { continue }
+		if raw && binary.BigEndian.Uint16(reply[4:]) != id { continue }
+		return time.Since(start), nil
+	}
+}
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 { sum += uint32(b[i])<<8 | uint32(b[i+1]) }
+	if len(b)%2 == 1 { sum += uint32(b[len(b)-1]) << 8 }
+	for sum>>16 != 0 { sum = sum&0xffff + sum>>16 }
+	return ^uint16(sum)
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenICMP opens an unprivileged ICMP datagram socket (allowed by
+// net.ipv4.ping_group_range) and falls back to a raw socket, which needs
+// CAP_NET_RAW. raw reports which kind was opened.
+func listenICMP(v6 bool) (conn net.PacketConn, raw bool, err error) {
+	family, proto, rawNet := syscall.AF_INET, syscall.IPPROTO_ICMP, "ip4:icmp"
+	var local syscall.Sockaddr = &syscall.SockaddrInet4{}
+	if v6 { family, proto, rawNet, local = syscall.AF_INET6, syscall.IPPROTO_ICMPV6, "ip6:ipv6-icmp", &syscall.SockaddrInet6{} }
+
+	if fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto); err == nil {
+		if err := syscall.Bind(fd, local); err == nil {
+			f := os.NewFile(uintptr(fd), "icmp")
+			conn, err := net.FilePacketConn(f)
+			f.Close()
+			if err == nil { return conn, false, nil }
+		} else {
+			syscall.Close(fd)
+		}
+	}
+	conn, err = net.ListenPacket(rawNet, "")
+	if err != nil { return nil, false, fmt.Errorf("no ICMP socket (allow net.ipv4.ping_group_range or grant CAP_NET_RAW): %w", err) }
+	return conn, true, nil
+}
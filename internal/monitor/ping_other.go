@@ -0,0 +1,12 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"errors"
+	"net"
+)
+
+func listenICMP(v6 bool) (net.PacketConn, bool, error) {
+	return nil, false, errors.New("ping monitors are only supported on Linux")
+}
@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"go-upkeep/internal/models"
+	"testing"
+)
+
+func TestICMPChecksum(t *testing.T) {
+	// The worked example from RFC 1071, section 3
+	if got := icmpChecksum([]byte{0x00, 0x01, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7}); got != 0x220d { t.Errorf("RFC 1071 example: %#04x, want 0x220d", got) }
+	if got := icmpChecksum([]byte{0x01}); got != 0xfeff { t.Errorf("odd length: %#04x, want 0xfeff", got) }
+
+	// An echo request carrying its checksum sums to zero
+	pkt := []byte{icmpv4EchoRequest, 0, 0, 0, 0x12, 0x34, 0x00, 0x01, 'c', 'o', 'o', 'k', 'i', 'e', '!', '!'}
+	binary.BigEndian.PutUint16(pkt[2:], icmpChecksum(pkt))
+	if got := icmpChecksum(pkt); got != 0 { t.Errorf("checksummed packet sums to %#04x, want 0", got) }
+}
+
+func TestPingHost(t *testing.T) {
+	tests := map[string]string{
+		"example.com":             "example.com",
+		" 10.0.0.1 ":              "10.0.0.1",
+		"https://example.com/x":   "example.com",
+		"example.com:443":         "example.com",
+		"[2001:db8::1]:22":        "2001:db8::1",
+		"[2001:db8::1]":           "2001:db8::1",
+		"icmp://[2001:db8::1]:0/": "2001:db8::1",
+	}
+	for in, want := range tests {
+		if got := pingHost(in); got != want { t.Errorf("pingHost(%q) = %q, want %q", in, got, want) }
+	}
+}
+
+func TestPingLoopback(t *testing.T) {
+	conn, _, err := listenICMP(false)
+	if err != nil { t.Skipf("no ICMP socket available: %v", err) }
+	conn.Close()
+
+	site, res := checkPing(context.Background(), models.Site{URL: "127.0.0.1", PacketCount: 2})
+	if res.Status != "UP" || site.PacketLoss != 0 || site.RTTMax < site.RTTMin { t.Errorf("loopback ping: %+v, loss %.0f%%, rtt %s-%s", res, site.PacketLoss, site.RTTMin, site.RTTMax) }
+}
+
+func TestPingUnresolvable(t *testing.T) {
+	site, res := checkPing(context.Background(), models.Site{URL: "no-such-host.invalid", PacketCount: 1})
+	if res.Status != "DOWN" || res.Error == "" || site.PacketLoss != 100 { t.Errorf("unresolvable host: %+v, loss %.0f%%", res, site.PacketLoss) }
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// --- REDIS ---
+// A minimal RESP client: enough to authenticate, select a database and run one
+// probe command. DSNs look like redis://[user:pass@]host:6379/0 (rediss:// for TLS).
+
+const defaultRedisProbe = "PING"
+
+func probeRedis(ctx context.Context, dsn, command string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return "", errors.New("redis DSN must look like redis://[user:pass@]host:port/db")
+	}
+	addr := u.Host
+	if u.Port() == "" { addr = net.JoinHostPort(u.Hostname(), "6379") }
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil { return "", err }
+	if u.Scheme == "rediss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil { conn.Close(); return "", err }
+		conn = tlsConn
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok { conn.SetDeadline(deadline) }
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	call := func(args ...string) (string, error) {
+		if _, err := conn.Write(respCommand(args)); err != nil { return "", err }
+		return readRESP(r)
+	}
+	if pass, ok := u.User.Password(); ok {
+		args := []string{"AUTH", pass}
+		if user := u.User.Username(); user != "" { args = []string{"AUTH", user, pass} }
+		if _, err := call(args...); err != nil { return "", fmt.Errorf("auth: %w", err) }
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" && db != "0" {
+		if _, err := call("SELECT", db); err != nil { return "", fmt.Errorf("select: %w", err) }
+	}
+	args := strings.Fields(command)
+	if len(args) == 0 { args = []string{defaultRedisProbe} }
+	return call(args...)
+}
+
+func respCommand(args []string) []byte {
+	b := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args { b = append(b, "$"+strconv.Itoa(len(a))+"\r\n"+a+"\r\n"...) }
+	return b
+}
+
+// readRESP reads one reply and flattens it to a scalar; arrays yield their first element.
+func readRESP(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil { return "", err }
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" { return "", errors.New("empty reply") }
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil { return "", err }
+		if n < 0 { return "", nil }
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil { return "", err }
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil { return "", err }
+		var first string
+		for i := 0; i < n; i++ {
+			v, err := readRESP(r)
+			if err != nil { return "", err }
+			if i == 0 { first = v }
+		}
+		return first, nil
+	}
+	return "", fmt.Errorf("unexpected reply %q", line)
+}
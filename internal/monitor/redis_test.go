@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRESP(t *testing.T) {
+	tests := []struct{ in, want, err string }{
+		{"+PONG\r\n", "PONG", ""},
+		{":42\r\n", "42", ""},
+		{"$5\r\nhello\r\n", "hello", ""},
+		{"$0\r\n\r\n", "", ""},
+		{"$-1\r\n", "", ""},
+		{"$4\r\na\r\nb\r\n", "a\r\nb", ""}, // Bulk strings are binary safe
+		{"*2\r\n$3\r\nfoo\r\n:1\r\n", "foo", ""},
+		{"*0\r\n", "", ""},
+		{"-ERR unknown command\r\n", "", "ERR unknown command"},
+		{"\r\n", "", "empty reply"},
+		{"!oops\r\n", "", "unexpected reply"},
+		{"$5\r\nhi\r\n", "", "EOF"},
+		{"*2\r\n+a\r\n", "", "EOF"},
+	}
+	for _, tt := range tests {
+		got, err := readRESP(bufio.NewReader(strings.NewReader(tt.in)))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) { t.Errorf("readRESP(%q) err = %v, want %q", tt.in, err, tt.err) }
+			continue
+		}
+		if err != nil || got != tt.want { t.Errorf("readRESP(%q) = %q, %v, want %q", tt.in, got, err, tt.want) }
+	}
+}
+
+func TestRespCommand(t *testing.T) {
+	if got := string(respCommand([]string{"GET", "a b"})); got != "*2\r\n$3\r\nGET\r\n$3\r\na b\r\n" { t.Errorf("respCommand = %q", got) }
+}
+
+// redisStub speaks enough RESP for the probe: AUTH against one password,
+// SELECT, PING, DBSIZE and GET greeting.
+func redisStub(t *testing.T, pass string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil { return }
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				authed, db := pass == "", "0"
+				for {
+					args, err := readCommand(r)
+					if err != nil { return }
+					cmd := strings.ToUpper(args[0]); reply := "-ERR unknown command\r\n"
+					switch {
+					case cmd == "AUTH" && args[len(args)-1] == pass: authed = true; reply = "+OK\r\n"
+					case cmd == "AUTH": reply = "-WRONGPASS invalid username-password pair\r\n"
+					case !authed: reply = "-NOAUTH Authentication required.\r\n"
+					case cmd == "SELECT": db = args[1]; reply = "+OK\r\n"
+					case cmd == "PING": reply = "+PONG\r\n"
+					case cmd == "DBSIZE": reply = ":42\r\n"
+					case cmd == "GET": v := "hello db" + db; reply = "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+					}
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// readCommand reads one RESP array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil { return nil, err }
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args { if args[i], err = readRESP(r); err != nil { return nil, err } }
+	return args, nil
+}
+
+func TestProbeRedisStub(t *testing.T) {
+	addr := redisStub(t, "s3cret")
+	tests := []struct{ dsn, command, want, err string }{
+		{"redis://:s3cret@" + addr, "", "PONG", ""},
+		{"redis://default:s3cret@" + addr + "/2", "GET greeting", "hello db2", ""},
+		{"redis://:s3cret@" + addr + "/0", "dbsize", "42", ""},
+		{"redis://:wrong@" + addr, "", "", "auth: WRONGPASS"},
+		{"redis://" + addr, "", "", "NOAUTH"},
+		{"http://" + addr, "", "", "redis DSN must look like"},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		got, err := probeRedis(ctx, tt.dsn, tt.command)
+		cancel()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) { t.Errorf("%s: err = %v, want %q", tt.dsn, err, tt.err) }
+			continue
+		}
+		if err != nil || got != tt.want { t.Errorf("%s %q = %q, %v, want %q", tt.dsn, tt.command, got, err, tt.want) }
+	}
+}
@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"container/heap"
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// --- SCHEDULER ---
+// A single timer heap decides which site is due next and hands it to a bounded
+// pool of workers. Each running check carries a context that is cancelled when
+// the site is deleted or its configuration changes.
+
+// MaxConcurrency caps how many checks run at once. Set before StartEngine.
+var MaxConcurrency = 16
+
+const (
+	minInterval  = 5 * time.Second
+	maxJitter    = 15 * time.Second
+	pausedRetry  = 5 * time.Second
+	rescheduleIn = 250 * time.Millisecond
+)
+
+type job struct {
+	id     int
+	next   time.Time
+	index  int                // Position in the heap, -1 while not queued
+	ctx    context.Context
+	cancel context.CancelFunc // Set while a check is running
+	dirty  bool               // Config changed mid-check; run again soon
+}
+
+type jobHeap []*job
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+func (h jobHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i]; h[i].index = i; h[j].index = j }
+func (h *jobHeap) Push(x any)        { j := x.(*job); j.index = len(*h); *h = append(*h, j) }
+func (h *jobHeap) Pop() any {
+	old := *h; n := len(old); j := old[n-1]
+	old[n-1] = nil; j.index = -1; *h = old[:n-1]
+	return j
+}
+
+type scheduler struct {
+	mu    sync.Mutex
+	queue jobHeap
+	jobs  map[int]*job
+	wake  chan struct{}
+	work  chan *job
+	start sync.Once
+}
+
+var sched = &scheduler{jobs: make(map[int]*job), wake: make(chan struct{}, 1), work: make(chan *job)}
+
+func (s *scheduler) run() {
+	s.start.Do(func() {
+		for i := 0; i < max(MaxConcurrency, 1); i++ { go s.worker() }
+		go s.loop()
+	})
+}
+
+// add queues a new site with a random offset so a fresh start doesn't check everything at once.
+func (s *scheduler) add(id int, interval time.Duration) {
+	s.mu.Lock(); defer s.mu.Unlock()
+	if _, ok := s.jobs[id]; ok { return }
+	j := &job{id: id, next: time.Now().Add(jitter(interval)), index: -1}
+	s.jobs[id] = j
+	heap.Push(&s.queue, j)
+	s.notify()
+}
+
+// runSoon moves a site to the front of the queue, cancelling a check already in
+// flight so the next run uses the current configuration.
+func (s *scheduler) runSoon(id int) {
+	s.mu.Lock(); defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok { return }
+	if j.index >= 0 {
+		j.next = time.Now(); heap.Fix(&s.queue, j.index)
+		s.notify()
+	} else if j.cancel != nil {
+		j.cancel(); j.dirty = true
+	}
+}
+
+// remove drops a site from the schedule and cancels any check in flight.
+func (s *scheduler) remove(id int) {
+	s.mu.Lock(); defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok { return }
+	delete(s.jobs, id)
+	if j.index >= 0 { heap.Remove(&s.queue, j.index) }
+	if j.cancel != nil { j.cancel() }
+}
+
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *scheduler) loop() {
+	timer := time.NewTimer(time.Hour)
+	for {
+		s.mu.Lock()
+		wait := time.Hour
+		if len(s.queue) > 0 { wait = time.Until(s.queue[0].next) }
+		if len(s.queue) > 0 && wait <= 0 {
+			j := heap.Pop(&s.queue).(*job)
+			j.ctx, j.cancel = context.WithCancel(context.Background())
+			s.mu.Unlock()
+			s.work <- j // Blocks while every worker is busy
+			continue
+		}
+		s.mu.Unlock()
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			if !timer.Stop() { select { case <-timer.C: default: } }
+		}
+	}
+}
+
+func (s *scheduler) worker() {
+	for j := range s.work {
+		active := IsEngineActive()
+		if active { checkByID(j.ctx, j.id) }
+		s.finish(j, active)
+	}
+}
+
+// finish requeues a job after its check, unless the site was removed meanwhile.
+func (s *scheduler) finish(j *job, active bool) {
+	delay, exists := pausedRetry, true
+	if active { delay, exists = nextDelay(j.id) }
+
+	s.mu.Lock(); defer s.mu.Unlock()
+	j.cancel(); j.cancel = nil; j.ctx = nil
+	if s.jobs[j.id] != j { return }
+	if !exists { delete(s.jobs, j.id); return }
+	if j.dirty { delay = rescheduleIn; j.dirty = false }
+	j.next = time.Now().Add(delay)
+	heap.Push(&s.queue, j)
+	s.notify()
+}
+
+// nextDelay returns how long to wait before checking a site again. Suspected
+// failures (between the first failed check and confirmation) use RetryInterval,
+// and push monitors wake up as soon as their heartbeat becomes overdue.
+func nextDelay(id int) (time.Duration, bool) {
+	Mutex.RLock(); site, ok := LiveState[id]; Mutex.RUnlock()
+	interval := site.Interval
+	if site.RetryInterval > 0 && site.FailureCount >= 1 && site.FailureCount <= site.MaxRetries { interval = site.RetryInterval }
+	delay := time.Duration(interval) * time.Second
+	if site.Type == "push" {
+		if deadline, err := PushDeadline(site); err == nil {
+			if until := time.Until(deadline) + time.Second; until > 0 && until < delay { delay = until }
+		}
+	}
+	return max(delay, minInterval), ok
+}
+
+func jitter(interval time.Duration) time.Duration {
+	limit := min(interval, maxJitter)
+	if limit <= 0 { return 0 }
+	return rand.N(limit)
+}
@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"container/heap"
+	"context"
+	"go-upkeep/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestScheduler() *scheduler {
+	return &scheduler{jobs: make(map[int]*job), wake: make(chan struct{}, 1), work: make(chan *job)}
+}
+
+// begin pops a job the way the loop does before handing it to a worker.
+func (s *scheduler) begin(t *testing.T, id int) *job {
+	t.Helper()
+	s.mu.Lock(); defer s.mu.Unlock()
+	j := s.jobs[id]
+	if j == nil || j.index < 0 { t.Fatalf("job %d not queued", id) }
+	heap.Remove(&s.queue, j.index)
+	j.ctx, j.cancel = context.WithCancel(context.Background())
+	return j
+}
+
+func TestJobHeapOrder(t *testing.T) {
+	now := time.Now()
+	var h jobHeap
+	for i, off := range []int{30, 5, 20, 0, 10} { heap.Push(&h, &job{id: i, next: now.Add(time.Duration(off) * time.Second)}) }
+	for i, j := range h { if j.index != i { t.Errorf("job %d has index %d at position %d", j.id, j.index, i) } }
+	var order []int
+	for h.Len() > 0 {
+		j := heap.Pop(&h).(*job)
+		if j.index != -1 { t.Errorf("popped job %d keeps index %d", j.id, j.index) }
+		order = append(order, j.id)
+	}
+	if want := []int{3, 1, 4, 2, 0}; !slices.Equal(order, want) { t.Errorf("pop order %v, want %v", order, want) }
+}
+
+func TestRunSoonQueued(t *testing.T) {
+	s := newTestScheduler()
+	s.add(1, time.Hour); s.add(2, time.Hour)
+	s.mu.Lock(); s.jobs[1].next = time.Now().Add(time.Hour); heap.Fix(&s.queue, s.jobs[1].index); s.mu.Unlock()
+	s.runSoon(1)
+	if s.queue[0].id != 1 || time.Until(s.queue[0].next) > 0 { t.Errorf("runSoon did not move job 1 to the front") }
+	s.runSoon(99) // Unknown sites are ignored
+}
+
+func TestRunSoonWhileRunning(t *testing.T) {
+	setLive(t, models.Site{ID: 1, Interval: 60})
+	s := newTestScheduler()
+	s.add(1, time.Minute)
+	j := s.begin(t, 1)
+	s.runSoon(1)
+	if j.ctx.Err() == nil || !j.dirty { t.Fatalf("running check not cancelled and marked dirty") }
+	s.finish(j, true)
+	if j.dirty || j.index < 0 || j.cancel != nil { t.Errorf("after finish: dirty %v, index %d", j.dirty, j.index) }
+	if d := time.Until(j.next); d > rescheduleIn || d < 0 { t.Errorf("dirty job requeued in %v, want about %v", d, rescheduleIn) }
+}
+
+func TestRemoveWhileRunning(t *testing.T) {
+	setLive(t, models.Site{ID: 1, Interval: 60})
+	s := newTestScheduler()
+	s.add(1, time.Minute)
+	j := s.begin(t, 1)
+	s.remove(1)
+	if j.ctx.Err() == nil { t.Error("remove did not cancel the running check") }
+	s.finish(j, true)
+	if len(s.queue) != 0 || len(s.jobs) != 0 { t.Errorf("removed job requeued: queue %d, jobs %d", len(s.queue), len(s.jobs)) }
+}
+
+func TestFinishDropsDeletedSite(t *testing.T) {
+	setLive(t) // Site vanished from LiveState while its check ran
+	s := newTestScheduler()
+	s.add(1, time.Minute)
+	s.finish(s.begin(t, 1), true)
+	if len(s.queue) != 0 || len(s.jobs) != 0 { t.Errorf("job for a deleted site kept: queue %d, jobs %d", len(s.queue), len(s.jobs)) }
+}
+
+func TestNextDelay(t *testing.T) {
+	base := models.Site{ID: 1, Type: "http", Interval: 60, RetryInterval: 10, MaxRetries: 3}
+	tests := []struct {
+		name string
+		edit func(*models.Site)
+		want time.Duration
+	}{
+		{"healthy", func(*models.Site) {}, time.Minute},
+		{"first failure", func(s *models.Site) { s.FailureCount = 1 }, 10 * time.Second},
+		{"last retry", func(s *models.Site) { s.FailureCount = 3 }, 10 * time.Second},
+		{"confirmed down", func(s *models.Site) { s.FailureCount = 4 }, time.Minute},
+		{"no retry interval", func(s *models.Site) { s.FailureCount = 1; s.RetryInterval = 0 }, time.Minute},
+		{"floor", func(s *models.Site) { s.Interval = 1 }, minInterval},
+	}
+	for _, tt := range tests {
+		site := base; tt.edit(&site)
+		setLive(t, site)
+		if got, ok := nextDelay(1); !ok || got != tt.want { t.Errorf("%s: nextDelay = %v, %v, want %v", tt.name, got, ok, tt.want) }
+	}
+	setLive(t)
+	if _, ok := nextDelay(1); ok { t.Error("nextDelay reported a missing site as existing") }
+}
+
+func TestNextDelayPushDeadline(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		lastBeat time.Duration // Before now
+		min, max time.Duration
+	}{
+		{"clamped to the deadline", 290 * time.Second, 15 * time.Second, 17 * time.Second}, // Due at +300s +5s grace, woken a second after
+		{"floor applies", 303 * time.Second, minInterval, minInterval},
+		{"fresh heartbeat", 0, 5 * time.Minute, 5 * time.Minute},
+		{"already overdue", time.Hour, 5 * time.Minute, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		setLive(t, models.Site{ID: 1, Type: "push", Interval: 300, LastHeartbeat: now.Add(-tt.lastBeat)})
+		if got, _ := nextDelay(1); got < tt.min || got > tt.max { t.Errorf("%s: nextDelay = %v, want %v..%v", tt.name, got, tt.min, tt.max) }
+	}
+}
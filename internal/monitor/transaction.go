@@ -0,0 +1,214 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- TRANSACTIONS ---
+// Steps run in order on one cookie jar, so sessions carry over. Values
+// extracted from a response are substituted as {{name}} in later steps.
+// The first failing step ends the run and is named in the error.
+
+const maxStepBody = 1 << 20
+
+var (
+	stepVar        = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+	stepVarName    = regexp.MustCompile(`^\w+$`)
+	stepMethods    = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	extractKinds   = []string{"json", "regex", "cookie", "header"}
+	assertionKinds = []string{"status", "body", "json", "header", "latency"}
+)
+
+// ValidateSteps checks a transaction definition before it is saved.
+func ValidateSteps(steps []models.Step) error {
+	if len(steps) == 0 { return errors.New("a transaction needs at least one step") }
+	for i, st := range steps {
+		label := stepLabel(i, st)
+		if strings.TrimSpace(st.URL) == "" { return fmt.Errorf("%s: url is required", label) }
+		if st.Method != "" && !slices.Contains(stepMethods, strings.ToUpper(st.Method)) { return fmt.Errorf("%s: unsupported method %q", label, st.Method) }
+		for _, ex := range st.Extract {
+			if !stepVarName.MatchString(ex.Var) { return fmt.Errorf("%s: variable name %q must be letters, digits or _", label, ex.Var) }
+			if !slices.Contains(extractKinds, ex.From) { return fmt.Errorf("%s: extract from must be one of %s", label, strings.Join(extractKinds, ", ")) }
+			if ex.Path == "" { return fmt.Errorf("%s: extract %s needs a path", label, ex.Var) }
+			if ex.From == "regex" {
+				if _, err := regexp.Compile(ex.Path); err != nil { return fmt.Errorf("%s: extract %s: %w", label, ex.Var, err) }
+			}
+		}
+		for _, a := range st.Assert {
+			if !slices.Contains(assertionKinds, a.Check) { return fmt.Errorf("%s: assert check must be one of %s", label, strings.Join(assertionKinds, ", ")) }
+			if (a.Check == "json" || a.Check == "header") && a.Path == "" { return fmt.Errorf("%s: %s assertion needs a path", label, a.Check) }
+			if a.Check == "body" {
+				if _, err := regexp.Compile(a.Expect); err != nil { return fmt.Errorf("%s: body assertion: %w", label, err) }
+			}
+		}
+	}
+	return nil
+}
+
+func stepLabel(i int, st models.Step) string {
+	if st.Name != "" { return fmt.Sprintf("step %d (%s)", i+1, st.Name) }
+	return fmt.Sprintf("step %d", i+1)
+}
+
+func checkTransaction(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	start := time.Now()
+	site.HasSSL, site.CertExpiry = false, time.Time{}
+	res := CheckResult{Status: "UP"}
+	client, err := clientFor(site)
+	if err == nil { err = ValidateSteps(site.Steps) }
+	if err != nil {
+		res.Status = "DOWN"; res.Error = err.Error()
+	} else {
+		jar, _ := cookiejar.New(nil)
+		c := *client; c.Jar = jar
+		vars := map[string]string{}
+		for i, st := range site.Steps {
+			code, err := runStep(ctx, &c, &site, &res, st, vars)
+			res.StatusCode = code
+			if err != nil { res.Status = "DOWN"; res.Error = stepLabel(i, st) + ": " + err.Error(); break }
+		}
+	}
+	res.Latency = time.Since(start)
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+func runStep(ctx context.Context, c *http.Client, site *models.Site, res *CheckResult, st models.Step, vars map[string]string) (int, error) {
+	var missing []string
+	expand := func(s string) string {
+		return stepVar.ReplaceAllStringFunc(s, func(m string) string {
+			name := stepVar.FindStringSubmatch(m)[1]
+			v, ok := vars[name]
+			if !ok { missing = append(missing, name) }
+			return v
+		})
+	}
+	method := strings.ToUpper(st.Method)
+	if method == "" { method = "GET"; if st.Body != "" { method = "POST" } }
+	url, body := expand(st.URL), expand(st.Body)
+	headers := make(map[string]string, len(st.Headers))
+	for k, v := range st.Headers { headers[k] = expand(v) }
+	if len(missing) > 0 { return 0, fmt.Errorf("undefined variable %s", strings.Join(missing, ", ")) }
+
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil { return 0, err }
+	setAuth(req, *site)
+	for k, v := range headers { req.Header.Set(k, v) }
+	if body != "" && req.Header.Get("Content-Type") == "" && json.Valid([]byte(body)) { req.Header.Set("Content-Type", "application/json") }
+
+	start := time.Now()
+	resp, err := c.Do(req)
+	if err != nil { return 0, err }
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxStepBody))
+	latency := time.Since(start)
+	if err != nil { return resp.StatusCode, err }
+	if resp.TLS != nil && !site.HasSSL { trackCert(site, res, resp.TLS) } // The first TLS step's certificate is tracked
+
+	var doc any
+	parsed := false
+	jsonDoc := func() (any, error) {
+		if !parsed {
+			parsed = true
+			d := json.NewDecoder(bytes.NewReader(respBody)); d.UseNumber()
+			if err := d.Decode(&doc); err != nil { return nil, fmt.Errorf("response is not JSON: %w", err) }
+		}
+		if doc == nil { return nil, errors.New("response is not JSON") }
+		return doc, nil
+	}
+
+	asserts := st.Assert
+	hasStatus := false
+	for _, a := range asserts { if a.Check == "status" { hasStatus = true } }
+	if !hasStatus { asserts = append([]models.Assertion{{Check: "status", Expect: "< 400"}}, asserts...) }
+	for _, a := range asserts {
+		var err error
+		switch a.Check {
+		case "status": err = assertScalar(strconv.Itoa(resp.StatusCode), a.Expect)
+		case "latency": err = assertScalar(strconv.FormatInt(latency.Milliseconds(), 10), a.Expect)
+		case "header": err = assertScalar(resp.Header.Get(a.Path), a.Expect)
+		case "body":
+			if !regexp.MustCompile(a.Expect).Match(respBody) { err = fmt.Errorf("body does not match %q", a.Expect) }
+		case "json":
+			d, jerr := jsonDoc()
+			if jerr != nil { err = jerr; break }
+			v, ok := jsonPath(d, a.Path)
+			if !ok { err = fmt.Errorf("json path %s not found", a.Path); break }
+			err = assertScalar(v, a.Expect)
+		}
+		if err != nil {
+			what := a.Check; if a.Path != "" { what += " " + a.Path }
+			return resp.StatusCode, fmt.Errorf("%s: %w", what, err)
+		}
+	}
+
+	for _, ex := range st.Extract {
+		var v string; ok := false
+		switch ex.From {
+		case "header": v = resp.Header.Get(ex.Path); ok = v != ""
+		case "cookie":
+			for _, ck := range resp.Cookies() { if ck.Name == ex.Path { v, ok = ck.Value, true } }
+			if !ok {
+				for _, ck := range c.Jar.Cookies(req.URL) { if ck.Name == ex.Path { v, ok = ck.Value, true } }
+			}
+		case "regex":
+			if m := regexp.MustCompile(ex.Path).FindSubmatch(respBody); m != nil {
+				v, ok = string(m[0]), true
+				if len(m) > 1 { v = string(m[1]) }
+			}
+		case "json":
+			d, err := jsonDoc()
+			if err != nil { return resp.StatusCode, fmt.Errorf("extract %s: %w", ex.Var, err) }
+			v, ok = jsonPath(d, ex.Path)
+		}
+		if !ok { return resp.StatusCode, fmt.Errorf("extract %s: %s %q not found", ex.Var, ex.From, ex.Path) }
+		vars[ex.Var] = v
+	}
+	return resp.StatusCode, nil
+}
+
+// jsonPath resolves a dotted path such as "data.items.0.id" (or "data.items[0].id",
+// optionally prefixed with "$.") and renders the value as text.
+func jsonPath(doc any, path string) (string, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	cur := doc
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch node := cur.(type) {
+			case map[string]any:
+				v, ok := node[key]
+				if !ok { return "", false }
+				cur = v
+			case []any:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(node) { return "", false }
+				cur = node[i]
+			default:
+				return "", false
+			}
+		}
+	}
+	switch v := cur.(type) {
+	case string: return v, true
+	case json.Number: return v.String(), true
+	case bool: return strconv.FormatBool(v), true
+	case nil: return "null", true
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+}
@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"go-upkeep/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc any
+	d := json.NewDecoder(strings.NewReader(`{"data":{"items":[{"id":7,"name":"a"},{"id":8}]},"ok":true,"none":null,"pi":3.14}`)); d.UseNumber()
+	if err := d.Decode(&doc); err != nil { t.Fatal(err) }
+	tests := []struct {
+		path, want string
+		ok         bool
+	}{
+		{"data.items.0.id", "7", true},
+		{"data.items[1].id", "8", true},
+		{"$.data.items[0].name", "a", true},
+		{"ok", "true", true},
+		{"none", "null", true},
+		{"pi", "3.14", true},
+		{"data.items.1", `{"id":8}`, true},
+		{"data.items.2", "", false},
+		{"data.items.x", "", false},
+		{"data.missing", "", false},
+		{"ok.deeper", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := jsonPath(doc, tt.path); got != tt.want || ok != tt.ok { t.Errorf("jsonPath(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.ok) }
+	}
+}
+
+// loginServer issues a session cookie and token on POST /login and serves
+// /items/{id} only to callers presenting both.
+func loginServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cret", Path: "/"})
+		w.Write([]byte(`{"token":"t-42","items":[{"id":7}]}`))
+	})
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ck, err := r.Cookie("session")
+		if err != nil || ck.Value != "s3cret" || r.Header.Get("Authorization") != "Bearer t-42" { http.Error(w, "forbidden", 403); return }
+		if r.PathValue("id") != "7" { http.NotFound(w, r); return }
+		w.Write([]byte(`{"ok":true,"session":"` + r.Header.Get("X-Session") + `"}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTransactionLoginFlow(t *testing.T) {
+	srv := loginServer(t)
+	site := models.Site{Type: "transaction", Steps: []models.Step{
+		{Name: "login", URL: srv.URL + "/login", Body: `{"user":"a"}`, Extract: []models.Extract{
+			{Var: "token", From: "json", Path: "token"},
+			{Var: "id", From: "json", Path: "items[0].id"},
+			{Var: "sess", From: "cookie", Path: "session"},
+		}},
+		{Name: "fetch", URL: srv.URL + "/items/{{id}}", Headers: map[string]string{"Authorization": "Bearer {{token}}", "X-Session": "{{ sess }}"},
+			Assert: []models.Assertion{{Check: "json", Path: "ok", Expect: "true"}, {Check: "json", Path: "session", Expect: "s3cret"}}},
+	}}
+	_, res := checkTransaction(context.Background(), site)
+	if res.Status != "UP" || res.StatusCode != 200 { t.Errorf("status = %s %d, error %q", res.Status, res.StatusCode, res.Error) }
+}
+
+func TestTransactionNamesFailingStep(t *testing.T) {
+	srv := loginServer(t)
+	tests := []struct {
+		steps []models.Step
+		want  string
+	}{
+		// No login first: no cookie, so the fetch is refused
+		{[]models.Step{{URL: srv.URL + "/login", Method: "POST"}, {Name: "fetch", URL: srv.URL + "/items/7"}}, "step 2 (fetch): status"},
+		{[]models.Step{{URL: srv.URL + "/items/{{id}}"}}, "step 1: undefined variable id"},
+		{[]models.Step{{Name: "login", URL: srv.URL + "/login", Method: "POST", Extract: []models.Extract{{Var: "x", From: "json", Path: "missing"}}}}, "step 1 (login): extract x"},
+	}
+	for _, tt := range tests {
+		_, res := checkTransaction(context.Background(), models.Site{Type: "transaction", Steps: tt.steps})
+		if res.Status != "DOWN" || !strings.HasPrefix(res.Error, tt.want) { t.Errorf("got %s %q, want DOWN %q...", res.Status, res.Error, tt.want) }
+	}
+}
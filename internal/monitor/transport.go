@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"go-upkeep/internal/models"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- HTTP TRANSPORTS ---
+// Transports hold connection pools, so they are shared between checks with the
+// same network configuration instead of being rebuilt for every request.
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxRedirects = 10
+)
+
+type transportKey struct {
+	proxy      string
+	clientCert [sha256.Size]byte // Hash of the certificate and key settings; zero when unset
+}
+
+var (
+	transports     = make(map[transportKey]*http.Transport)
+	transportMutex sync.Mutex
+)
+
+func keyFor(site models.Site) transportKey {
+	key := transportKey{proxy: site.ProxyURL}
+	if site.ClientCert != "" { key.clientCert = sha256.Sum256([]byte(site.ClientCert + "\x00" + site.ClientKey)) }
+	return key
+}
+
+func transportFor(site models.Site) (*http.Transport, error) {
+	key := keyFor(site)
+	transportMutex.Lock(); defer transportMutex.Unlock()
+	if t, ok := transports[key]; ok { return t, nil }
+
+	t := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, MaxIdleConnsPerHost: 2, IdleConnTimeout: 90 * time.Second}
+	if site.ProxyURL != "" {
+		// net/http handles http, https and socks5 proxy schemes
+		proxy, err := url.Parse(site.ProxyURL)
+		if err != nil { return nil, fmt.Errorf("invalid proxy URL: %w", err) }
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	if site.ClientCert != "" {
+		cert, err := loadClientCert(site.ClientCert, site.ClientKey)
+		if err != nil { return nil, fmt.Errorf("client certificate: %w", err) }
+		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	transports[key] = t
+	return t, nil
+}
+
+// pruneTransports closes and drops the transports no site in sites uses any more.
+func pruneTransports(sites []models.Site) {
+	used := make(map[transportKey]bool, len(sites))
+	for _, s := range sites { used[keyFor(s)] = true }
+	transportMutex.Lock(); defer transportMutex.Unlock()
+	for key, t := range transports {
+		if !used[key] { t.CloseIdleConnections(); delete(transports, key) }
+	}
+}
+
+// clientFor builds a client applying the site's timeout and redirect policy on a pooled transport.
+func clientFor(site models.Site) (*http.Client, error) {
+	t, err := transportFor(site)
+	if err != nil { return nil, err }
+	timeout := defaultTimeout
+	if site.Timeout > 0 { timeout = time.Duration(site.Timeout) * time.Second }
+	return &http.Client{Timeout: timeout, Transport: t, CheckRedirect: redirectPolicy(site)}, nil
+}
+
+func redirectPolicy(site models.Site) func(*http.Request, []*http.Request) error {
+	if site.RedirectPolicy == "none" || site.RedirectPolicy == "fail" {
+		return func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+	limit := site.MaxRedirects
+	if limit <= 0 { limit = defaultMaxRedirects }
+	return func(_ *http.Request, via []*http.Request) error {
+		if len(via) >= limit { return fmt.Errorf("stopped after %d redirects", limit) }
+		return nil
+	}
+}
+
+// loadClientCert accepts inline PEM or a path to a PEM file for both parts.
+func loadClientCert(cert, key string) (tls.Certificate, error) {
+	pemOrFile := func(v string) ([]byte, error) {
+		if strings.Contains(v, "-----BEGIN") { return []byte(v), nil }
+		return os.ReadFile(v)
+	}
+	certPEM, err := pemOrFile(cert)
+	if err != nil { return tls.Certificate{}, err }
+	keyPEM, err := pemOrFile(key)
+	if err != nil { return tls.Certificate{}, err }
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// setAuth adds basic or bearer credentials to a check request.
+func setAuth(req *http.Request, site models.Site) {
+	if site.AuthToken != "" { req.Header.Set("Authorization", "Bearer "+site.AuthToken); return }
+	if site.AuthUser != "" { req.SetBasicAuth(site.AuthUser, site.AuthPass) }
+}
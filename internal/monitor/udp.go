@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// --- UDP ---
+// Sends Payload in one datagram and waits for a reply until the timeout.
+// "hex:" prefixes mark binary payloads ("hex:ff ff ff ff 54"); an Expect
+// starting with "hex:" is a regex over the hex-encoded reply.
+
+const maxDatagram = 64 << 10
+
+func checkUDP(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(site)); defer cancel()
+	start := time.Now()
+	reply, err := udpExchange(ctx, site.URL, site.Payload)
+	res := CheckResult{Status: "UP", Latency: time.Since(start)}
+	if err == nil { err = matchUDPReply(reply, site.Expect) }
+	if err != nil { res.Status = "DOWN"; res.Error = err.Error() }
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+// ParseUDPPayload decodes a payload in text or "hex:" form.
+func ParseUDPPayload(s string) ([]byte, error) {
+	if h, ok := strings.CutPrefix(s, "hex:"); ok {
+		b, err := hex.DecodeString(strings.Join(strings.Fields(h), ""))
+		if err != nil { return nil, fmt.Errorf("invalid hex payload: %w", err) }
+		return b, nil
+	}
+	return []byte(s), nil
+}
+
+func udpExchange(ctx context.Context, addr, payload string) ([]byte, error) {
+	msg, err := ParseUDPPayload(payload)
+	if err != nil { return nil, err }
+	if len(msg) == 0 { return nil, errors.New("a UDP probe needs a payload to send") }
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil { return nil, err }
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok { conn.SetDeadline(deadline) }
+	if _, err := conn.Write(msg); err != nil { return nil, err }
+
+	buf := make([]byte, maxDatagram)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() { return nil, errors.New("no reply before timeout") }
+		return nil, err // e.g. connection refused from an ICMP port unreachable
+	}
+	return buf[:n], nil
+}
+
+func matchUDPReply(reply []byte, expect string) error {
+	if expect == "" { return nil }
+	subject, pattern := reply, expect
+	if p, ok := strings.CutPrefix(expect, "hex:"); ok { subject, pattern = []byte(hex.EncodeToString(reply)), p }
+	re, err := regexp.Compile(pattern)
+	if err != nil { return fmt.Errorf("invalid expect pattern: %w", err) }
+	if !re.Match(subject) { return fmt.Errorf("reply %q does not match %q", limitReply(subject), expect) }
+	return nil
+}
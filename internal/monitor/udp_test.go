@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"go-upkeep/internal/models"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseUDPPayload(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []byte
+		err  bool
+	}{
+		{"ping", []byte("ping"), false},
+		{"", []byte{}, false},
+		{"hex:ff ff ff ff 54", []byte{0xff, 0xff, 0xff, 0xff, 0x54}, false},
+		{"hex:DEADbeef", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"hex: 0a\n0b ", []byte{0x0a, 0x0b}, false},
+		{"HEX:0a", []byte("HEX:0a"), false}, // The prefix is case-sensitive
+		{"hex:f", nil, true},
+		{"hex:zz", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUDPPayload(tt.in)
+		if (err != nil) != tt.err || !bytes.Equal(got, tt.want) { t.Errorf("ParseUDPPayload(%q) = %x, %v", tt.in, got, err) }
+	}
+}
+
+func TestMatchUDPReply(t *testing.T) {
+	tests := []struct {
+		reply, expect string
+		pass          bool
+	}{
+		{"anything", "", true},
+		{"pong:1", "^pong", true},
+		{"pong:1", "^ping", false},
+		{"\xff\xff\xff\xffI", "hex:^ffffffff49$", true},
+		{"\xff\xff\xff\xffI", "hex:^FFFFFFFF", false}, // Hex is lowercase
+		{"x", "(", false},
+	}
+	for _, tt := range tests {
+		if err := matchUDPReply([]byte(tt.reply), tt.expect); (err == nil) != tt.pass { t.Errorf("matchUDPReply(%q, %q) = %v", tt.reply, tt.expect, err) }
+	}
+}
+
+// udpStub answers A2S-style queries with an info header, ignores "silent" and
+// echoes anything else behind "pong:".
+func udpStub(t *testing.T) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil { return }
+			msg := buf[:n]
+			switch {
+			case bytes.HasPrefix(msg, []byte("\xff\xff\xff\xffT")): pc.WriteTo([]byte("\xff\xff\xff\xffIstub server"), from)
+			case string(msg) == "silent":
+			default: pc.WriteTo(append([]byte("pong:"), msg...), from)
+			}
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestUDPStub(t *testing.T) {
+	addr := udpStub(t)
+	tests := []struct{ payload, expect, status, err string }{
+		{"hello", "^pong:hello$", "UP", ""},
+		{"hex:ff ff ff ff 54 53 6f 75 72 63 65", "hex:^ffffffff49", "UP", ""},
+		{"hello", "^bye", "DOWN", "reply \"pong:hello\" does not match"},
+		{"silent", "", "DOWN", "no reply before timeout"},
+		{"", "", "DOWN", "a UDP probe needs a payload"},
+		{"hex:xyz", "", "DOWN", "invalid hex payload"},
+	}
+	for _, tt := range tests {
+		_, res := checkUDP(context.Background(), models.Site{Type: "udp", URL: addr, Payload: tt.payload, Expect: tt.expect, Timeout: 1})
+		if res.Status != tt.status || !strings.HasPrefix(res.Error, tt.err) { t.Errorf("%q: got %s %q, want %s %q", tt.payload, res.Status, res.Error, tt.status, tt.err) }
+	}
+}
@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-upkeep/internal/models"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// --- WEBSOCKET ---
+// Completes the RFC 6455 upgrade handshake by hand, then optionally sends
+// Payload as a text frame and waits for a reply matching the Expect regex.
+// Latency is the handshake time; the exchange only decides UP or DOWN.
+
+const (
+	wsGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsMaxMessage = 1 << 20
+)
+
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+type wsConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func checkWebSocket(ctx context.Context, site models.Site) (models.Site, CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(site)); defer cancel()
+	site.HasSSL, site.CertExpiry = false, time.Time{}
+	start := time.Now()
+	conn, state, err := wsDial(ctx, site)
+	res := CheckResult{Status: "UP", Latency: time.Since(start)}
+	if err != nil {
+		res.Status = "DOWN"; res.Error = err.Error()
+	} else {
+		defer conn.Close()
+		if state != nil { trackCert(&site, &res, state) }
+		if site.Payload != "" || site.Expect != "" {
+			if err := wsExchange(conn, site.Payload, site.Expect); err != nil { res.Status = "DOWN"; res.Error = err.Error() }
+		}
+		conn.writeFrame(wsClose, binary.BigEndian.AppendUint16(nil, 1000)) // Normal closure; the reply is not awaited
+	}
+	site.Latency = res.Latency; site.LastCheck = time.Now()
+	return site, res
+}
+
+func wsDial(ctx context.Context, site models.Site) (*wsConn, *tls.ConnectionState, error) {
+	u, err := url.Parse(site.URL)
+	if err != nil || u.Host == "" { return nil, nil, errors.New("URL must look like ws://host/path or wss://host/path") }
+	secure, port, scheme := false, "80", "http"
+	switch u.Scheme {
+	case "ws", "http":
+	case "wss", "https": secure, port, scheme = true, "443", "https"
+	default: return nil, nil, fmt.Errorf("unsupported scheme %q, use ws:// or wss://", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" { addr = net.JoinHostPort(u.Hostname(), port) }
+
+	var d net.Dialer
+	raw, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil { return nil, nil, err }
+	if deadline, ok := ctx.Deadline(); ok { raw.SetDeadline(deadline) }
+	var state *tls.ConnectionState
+	if secure {
+		tlsConn := tls.Client(raw, &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: true})
+		if err := tlsConn.HandshakeContext(ctx); err != nil { raw.Close(); return nil, nil, err }
+		cs := tlsConn.ConnectionState(); state = &cs
+		raw = tlsConn
+	}
+	conn := &wsConn{Conn: raw, r: bufio.NewReader(raw)}
+
+	nonce := make([]byte, 16); rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	httpURL := *u; httpURL.Scheme = scheme
+	req, err := http.NewRequestWithContext(ctx, "GET", httpURL.String(), nil)
+	if err != nil { conn.Close(); return nil, nil, err }
+	req.Header.Set("Upgrade", "websocket"); req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key); req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("User-Agent", "GoUpkeep/1.0")
+	setAuth(req, site)
+	if err := req.Write(conn); err != nil { conn.Close(); return nil, nil, err }
+
+	resp, err := http.ReadResponse(conn.r, req)
+	if err != nil { conn.Close(); return nil, state, fmt.Errorf("handshake: %w", err) }
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols { conn.Close(); return nil, state, fmt.Errorf("handshake refused: HTTP %s", resp.Status) }
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close(); return nil, state, errors.New("handshake: invalid Sec-WebSocket-Accept")
+	}
+	return conn, state, nil
+}
+
+// wsExchange sends payload (if any) and reads messages until one matches expect.
+// With no expectation the first message of any kind is enough.
+func wsExchange(conn *wsConn, payload, expect string) error {
+	var re *regexp.Regexp
+	if expect != "" {
+		var err error
+		if re, err = regexp.Compile(expect); err != nil { return fmt.Errorf("invalid expect pattern: %w", err) }
+	}
+	if payload != "" {
+		if err := conn.writeFrame(wsText, []byte(payload)); err != nil { return err }
+	}
+	var last []byte; got := false
+	for {
+		msg, err := conn.readMessage()
+		if err != nil {
+			if got { return fmt.Errorf("no reply matching %q (last: %q)", expect, limitReply(last)) }
+			if ne, ok := err.(net.Error); ok && ne.Timeout() { return errors.New("no reply before timeout") }
+			return err
+		}
+		if re == nil || re.Match(msg) { return nil }
+		last = msg; got = true
+	}
+}
+
+func limitReply(b []byte) string {
+	if len(b) > 200 { return string(b[:200]) + "..." }
+	return string(b)
+}
+
+// writeFrame sends a single masked frame, as clients must.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126: frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF: frame = binary.BigEndian.AppendUint16(append(frame, 0x80|126), uint16(n))
+	default: frame = binary.BigEndian.AppendUint64(append(frame, 0x80|127), uint64(n))
+	}
+	mask := make([]byte, 4); rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload { frame = append(frame, b^mask[i%4]) }
+	_, err := c.Write(frame)
+	return err
+}
+
+// readMessage returns the next text or binary message, reassembling fragments
+// and answering pings along the way.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil { return nil, err }
+		switch opcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil { return nil, err }
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			if len(payload) >= 2 { return nil, fmt.Errorf("server closed connection (code %d)", binary.BigEndian.Uint16(payload)) }
+			return nil, errors.New("server closed connection")
+		}
+		msg = append(msg, payload...)
+		if len(msg) > wsMaxMessage { return nil, errors.New("message too large") }
+		if fin { return msg, nil }
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err = io.ReadFull(c.r, hdr[:]); err != nil { return }
+	fin, opcode = hdr[0]&0x80 != 0, hdr[0]&0x0F
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil { return }
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil { return }
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxMessage { err = errors.New("message too large"); return }
+	var mask [4]byte
+	masked := hdr[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(c.r, mask[:]); err != nil { return }
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.r, payload); err != nil { return }
+	if masked { for i := range payload { payload[i] ^= mask[i%4] } }
+	if opcode != wsContinuation && opcode != wsText && opcode != wsBinary && opcode < wsClose { err = fmt.Errorf("unknown opcode %d", opcode) }
+	return
+}
@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"go-upkeep/internal/models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serverFrame builds an unmasked frame as a server sends it.
+func serverFrame(fin bool, opcode byte, payload []byte) []byte {
+	b0 := opcode; if fin { b0 |= 0x80 }
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n < 126: frame = append(frame, byte(n))
+	case n <= 0xFFFF: frame = binary.BigEndian.AppendUint16(append(frame, 126), uint16(n))
+	default: frame = binary.BigEndian.AppendUint64(append(frame, 127), uint64(n))
+	}
+	return append(frame, payload...)
+}
+
+// bufConn reads scripted server bytes and records what the client writes.
+type bufConn struct {
+	net.Conn
+	out bytes.Buffer
+}
+
+func (b *bufConn) Write(p []byte) (int, error) { return b.out.Write(p) }
+
+func scripted(frames ...[]byte) (*wsConn, *bufConn) {
+	bc := &bufConn{}
+	return &wsConn{Conn: bc, r: bufio.NewReader(bytes.NewReader(bytes.Join(frames, nil)))}, bc
+}
+
+func TestWSFrameRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 300, 0xFFFF, 70000} {
+		payload := bytes.Repeat([]byte("x"), n)
+		c, bc := scripted()
+		if err := c.writeFrame(wsText, payload); err != nil { t.Fatal(err) }
+		raw := bc.out.Bytes()
+		if raw[1]&0x80 == 0 { t.Errorf("len %d: client frame not masked", n) }
+		if n > 0 && bytes.Contains(raw, payload) { t.Errorf("len %d: payload sent in the clear", n) }
+		fin, op, got, err := (&wsConn{r: bufio.NewReader(bytes.NewReader(raw))}).readFrame()
+		if err != nil || !fin || op != wsText || !bytes.Equal(got, payload) { t.Errorf("len %d: got fin=%v op=%d len=%d err=%v", n, fin, op, len(got), err) }
+	}
+}
+
+func TestWSReadMessage(t *testing.T) {
+	big := bytes.Repeat([]byte("y"), 70000)
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   string
+		err    string
+	}{
+		{"single", [][]byte{serverFrame(true, wsText, []byte("hello"))}, "hello", ""},
+		{"fragmented around ping", [][]byte{serverFrame(false, wsText, []byte("hel")), serverFrame(true, wsPing, []byte("p")), serverFrame(true, wsContinuation, []byte("lo"))}, "hello", ""},
+		{"pong skipped", [][]byte{serverFrame(true, wsPong, nil), serverFrame(true, wsBinary, []byte{1, 2})}, "\x01\x02", ""},
+		{"16-bit length", [][]byte{serverFrame(true, wsText, big[:300])}, string(big[:300]), ""},
+		{"64-bit length", [][]byte{serverFrame(true, wsText, big)}, string(big), ""},
+		{"close with code", [][]byte{serverFrame(true, wsClose, binary.BigEndian.AppendUint16(nil, 1001))}, "", "code 1001"},
+		{"close without code", [][]byte{serverFrame(true, wsClose, nil)}, "", "server closed connection"},
+		{"unknown opcode", [][]byte{serverFrame(true, 0x3, nil)}, "", "unknown opcode 3"},
+		{"oversized", [][]byte{binary.BigEndian.AppendUint64([]byte{0x81, 127}, wsMaxMessage+1)}, "", "too large"},
+		{"truncated", [][]byte{serverFrame(true, wsText, []byte("hello"))[:4]}, "", "EOF"},
+	}
+	for _, tt := range tests {
+		c, _ := scripted(tt.frames...)
+		got, err := c.readMessage()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) { t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err) }
+			continue
+		}
+		if err != nil || string(got) != tt.want { t.Errorf("%s: got %d bytes, %v", tt.name, len(got), err) }
+	}
+}
+
+func TestWSPingAnswered(t *testing.T) {
+	c, bc := scripted(serverFrame(true, wsPing, []byte("p")), serverFrame(true, wsText, []byte("hi")))
+	if _, err := c.readMessage(); err != nil { t.Fatal(err) }
+	_, op, pong, err := (&wsConn{r: bufio.NewReader(&bc.out)}).readFrame()
+	if err != nil || op != wsPong || string(pong) != "p" { t.Errorf("ping answered with op %d %q, %v", op, pong, err) }
+}
+
+// echoServer upgrades /ws and answers each text frame with "echo: " + text.
+func echoServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if r.URL.Path != "/ws" || key == "" { http.Error(w, "not a websocket", 400); return }
+		raw, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil { return }
+		defer raw.Close()
+		sum := sha1.Sum([]byte(key + wsGUID))
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+		rw.Flush()
+		c := &wsConn{Conn: raw, r: rw.Reader}
+		for {
+			_, op, payload, err := c.readFrame()
+			if err != nil || op == wsClose { return }
+			raw.Write(serverFrame(true, wsText, append([]byte("echo: "), payload...)))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebSocketLoopback(t *testing.T) {
+	srv := echoServer(t)
+	base := "ws://" + strings.TrimPrefix(srv.URL, "http://")
+	tests := []struct{ url, payload, expect, status, err string }{
+		{base + "/ws", "", "", "UP", ""}, // Handshake only
+		{base + "/ws", "ping", "^echo: ping$", "UP", ""},
+		{base + "/ws", "ping", "^pong$", "DOWN", "no reply matching"},
+		{base + "/other", "", "", "DOWN", "handshake refused: HTTP 400"},
+	}
+	for _, tt := range tests {
+		site := models.Site{Type: "websocket", URL: tt.url, Payload: tt.payload, Expect: tt.expect, Timeout: 1}
+		_, res := checkWebSocket(context.Background(), site)
+		if res.Status != tt.status || !strings.HasPrefix(res.Error, tt.err) { t.Errorf("%s %q: got %s %q, want %s %q", tt.url, tt.payload, res.Status, res.Error, tt.status, tt.err) }
+	}
+}
@@ -2,13 +2,18 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-upkeep/internal/models"
 	"go-upkeep/internal/monitor"
 	"go-upkeep/internal/store"
 	"html/template"
+	"io"
 	"net/http"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ServerConfig struct {
@@ -16,21 +21,39 @@ type ServerConfig struct {
 	EnableStatus bool
 	Title        string
 	ClusterKey   string // Shared Secret for Security
+	PublicURL    string // Base URL push clients use; defaults to http://localhost:<Port>
+	GroupByTag   string // Status page sections by this tag key ("team" groups on team:*); ?group= overrides
 }
 
 func Start(cfg ServerConfig) {
+	mux := newMux(cfg)
+	go func() {
+		addr := fmt.Sprintf(":%d", cfg.Port)
+		fmt.Printf("HTTP Server listening on %s\n", addr)
+		http.ListenAndServe(addr, mux)
+	}()
+}
+
+// newMux registers the API routes, and the status pages when enabled.
+func newMux(cfg ServerConfig) *http.ServeMux {
 	mux := http.NewServeMux()
+	monitor.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+	if monitor.PublicURL == "" { monitor.PublicURL = fmt.Sprintf("http://localhost:%d", cfg.Port) }
 
-	// 1. Push Heartbeat
-	mux.HandleFunc("/api/push", func(w http.ResponseWriter, r *http.Request) {
-		token := r.URL.Query().Get("token")
+	// 1. Push Heartbeat (?token= or /api/push/{token})
+	pushHandler := func(w http.ResponseWriter, r *http.Request) {
+		token, push, err := parsePush(r)
+		if err != nil { http.Error(w, err.Error(), 400); return }
+		if t := r.PathValue("token"); t != "" { token = t }
 		if token == "" { http.Error(w, "Missing token", 400); return }
-		if monitor.RecordHeartbeat(token) {
+		if monitor.RecordHeartbeat(token, push) {
 			w.WriteHeader(http.StatusOK); w.Write([]byte("OK"))
 		} else {
 			http.Error(w, "Invalid Token", 404)
 		}
-	})
+	}
+	mux.HandleFunc("/api/push", pushHandler)
+	mux.HandleFunc("/api/push/{token}", pushHandler)
 
 	// 2. Health Check (For Cluster Follower)
 	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
@@ -64,6 +87,20 @@ func Start(cfg ServerConfig) {
 			http.Error(w, "Invalid JSON", 400)
 			return
 		}
+		for _, s := range data.Sites {
+			for _, pid := range s.ParentIDs {
+				if pid == s.ID || models.DependsOn(data.Sites, pid, s.ID) {
+					http.Error(w, fmt.Sprintf("Dependency cycle: site %d and parent %d depend on each other", s.ID, pid), 400)
+					return
+				}
+			}
+			for _, mb := range s.Members {
+				if mb.ID != s.ID && models.GroupContains(data.Sites, mb.ID, s.ID) {
+					http.Error(w, fmt.Sprintf("Group cycle: group %d and member %d contain each other", s.ID, mb.ID), 400)
+					return
+				}
+			}
+		}
 		if err := store.Get().ImportData(data); err != nil {
 			http.Error(w, "Import Failed: "+err.Error(), 500)
 			return
@@ -71,31 +108,180 @@ func Start(cfg ServerConfig) {
 		w.Write([]byte("Import Successful"))
 	})
 
-	// 5. Status Page
+	// 5. On-demand Check
+	mux.HandleFunc("POST /api/sites/{id}/check", func(w http.ResponseWriter, r *http.Request) {
+		if !requireSecret(w, r, cfg.ClusterKey) { return }
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil { http.Error(w, "Invalid site ID", 400); return }
+		res, err := monitor.CheckNow(id)
+		if errors.Is(err, monitor.ErrSiteNotFound) { http.Error(w, err.Error(), 404); return }
+		if errors.Is(err, monitor.ErrCheckTimeout) { http.Error(w, err.Error(), 504); return }
+		if err != nil { http.Error(w, err.Error(), 503); return }
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": id, "status": res.Status, "code": res.StatusCode,
+			"latency_ms": res.Latency.Milliseconds(), "error": res.Error, "checked_at": res.CheckedAt,
+		})
+	})
+
+	// 6. Push Token Rotation
+	mux.HandleFunc("POST /api/sites/{id}/rotate-token", func(w http.ResponseWriter, r *http.Request) {
+		if !requireSecret(w, r, cfg.ClusterKey) { return }
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil { http.Error(w, "Invalid site ID", 400); return }
+		token, ok := store.Get().RotateToken(id)
+		if !ok { http.Error(w, "No push monitor with that ID", 404); return }
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"id": id, "token": token, "push_url": monitor.PushURL(token)})
+	})
+
+	// 7. Transaction Steps Import (JSON array of steps)
+	mux.HandleFunc("PUT /api/sites/{id}/steps", func(w http.ResponseWriter, r *http.Request) {
+		if !requireSecret(w, r, cfg.ClusterKey) { return }
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil { http.Error(w, "Invalid site ID", 400); return }
+		site, ok := store.Get().GetSite(id)
+		if !ok || site.Type != "transaction" { http.Error(w, "No transaction monitor with that ID", 404); return }
+		var steps []models.Step
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&steps); err != nil { http.Error(w, "Invalid JSON", 400); return }
+		if err := monitor.ValidateSteps(steps); err != nil { http.Error(w, err.Error(), 400); return }
+		site.Steps = steps
+		store.Get().UpdateSite(site)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"id": id, "steps": len(steps)})
+	})
+
+	// 8. Site Listing (?tag=env:prod&tag=team selects by tag)
+	mux.HandleFunc("GET /api/sites", func(w http.ResponseWriter, r *http.Request) {
+		if !requireSecret(w, r, cfg.ClusterKey) { return }
+		sites := []models.Site{}
+		for _, s := range liveSites(tagSelectors(r)) {
+			red := s.Redacted(); if s.Token != "" { red.Token = models.RedactedSecret } // Fetch one site to read it
+			sites = append(sites, red)
+		}
+		sort.Slice(sites, func(i, j int) bool { return sites[i].ID < sites[j].ID })
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sites)
+	})
+
+	// 9. Single Site, with its push token so callers can build the push URL
+	mux.HandleFunc("GET /api/sites/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !requireSecret(w, r, cfg.ClusterKey) { return }
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil { http.Error(w, "Invalid site ID", 400); return }
+		monitor.Mutex.RLock(); s, ok := monitor.LiveState[id]; monitor.Mutex.RUnlock()
+		if !ok { http.Error(w, "Site not found", 404); return }
+		red := s.Redacted(); red.Token = s.Token
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(red)
+	})
+
+	// 10. Status Page (?tag= filters, ?group= picks the tag key for sections)
 	if cfg.EnableStatus {
-		mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) { renderStatusPage(w, cfg.Title) })
+		mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+			groupBy := cfg.GroupByTag
+			if r.URL.Query().Has("group") { groupBy = r.URL.Query().Get("group") }
+			renderStatusPage(w, cfg.Title, liveSites(tagSelectors(r)), strings.ToLower(strings.TrimSpace(groupBy)))
+		})
 		mux.HandleFunc("/status/json", func(w http.ResponseWriter, r *http.Request) {
-			monitor.Mutex.RLock(); defer monitor.Mutex.RUnlock()
+			sites := make(map[int]models.Site)
+			for _, s := range liveSites(tagSelectors(r)) { sites[s.ID] = s.Redacted() }
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(monitor.LiveState)
+			json.NewEncoder(w).Encode(sites)
+		})
+		// Server-Sent Events stream of config changes so open status pages refresh instantly
+		mux.HandleFunc("/status/events", func(w http.ResponseWriter, r *http.Request) {
+			flusher, ok := w.(http.Flusher)
+			if !ok { http.Error(w, "Streaming unsupported", 500); return }
+			w.Header().Set("Content-Type", "text/event-stream"); w.Header().Set("Cache-Control", "no-cache")
+			events, unsubscribe := store.Subscribe(); defer unsubscribe()
+			flusher.Flush()
+			for {
+				select {
+				case e := <-events:
+					payload, _ := json.Marshal(e)
+					fmt.Fprintf(w, "data: %s\n\n", payload); flusher.Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
 		})
 	}
+	return mux
+}
 
-	go func() {
-		addr := fmt.Sprintf(":%d", cfg.Port)
-		fmt.Printf("HTTP Server listening on %s\n", addr)
-		http.ListenAndServe(addr, mux)
-	}()
+// tagSelectors collects the ?tag= parameters; each may hold several tags.
+func tagSelectors(r *http.Request) []string {
+	return store.SplitTags(strings.Join(r.URL.Query()["tag"], ","))
 }
 
-func renderStatusPage(w http.ResponseWriter, title string) {
-	monitor.Mutex.RLock()
+// liveSites copies the monitored sites that match every selector.
+func liveSites(selectors []string) []models.Site {
+	monitor.Mutex.RLock(); defer monitor.Mutex.RUnlock()
 	var sites []models.Site
 	for _, s := range monitor.LiveState {
-		sites = append(sites, s)
+		if s.MatchesTags(selectors) { sites = append(sites, s) }
 	}
-	monitor.Mutex.RUnlock()
-	
+	return sites
+}
+
+// requireSecret rejects the request unless it carries the cluster secret; API
+// writes are disabled entirely while no secret is configured.
+func requireSecret(w http.ResponseWriter, r *http.Request, key string) bool {
+	if key == "" || r.Header.Get("X-Upkeep-Secret") != key {
+		http.Error(w, "Unauthorized: UPKEEP_CLUSTER_SECRET required", 401)
+		return false
+	}
+	return true
+}
+
+const maxPushMessage = 500
+
+// parsePush reads token, status, msg and ping/duration from the query string,
+// a form body or a JSON body.
+func parsePush(r *http.Request) (string, monitor.Push, error) {
+	var push monitor.Push
+	if err := r.ParseForm(); err != nil { return "", push, errors.New("Invalid parameters") }
+	params := make(map[string]string)
+	for k := range r.Form { params[k] = r.Form.Get(k) }
+	if r.Method == "POST" && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]any
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&body); err != nil { return "", push, errors.New("Invalid JSON") }
+		for k, v := range body { if v != nil { params[k] = fmt.Sprint(v) } }
+	}
+
+	switch strings.ToLower(params["status"]) {
+	case "", "up":
+	case "down": push.Down = true
+	case "start": push.Start = true
+	default: return "", push, errors.New("status must be up, down or start")
+	}
+	push.Message = limitMessage(params["msg"])
+	for _, key := range []string{"ping", "duration"} {
+		if v := params[key]; v != "" {
+			d, err := parseDuration(v)
+			if err != nil { return "", push, fmt.Errorf("invalid %s: %s", key, v) }
+			push.Duration = d
+		}
+	}
+	return params["token"], push, nil
+}
+
+// parseDuration accepts milliseconds ("1500") or a Go duration ("1m30s").
+func parseDuration(v string) (time.Duration, error) {
+	if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 { return time.Duration(ms * float64(time.Millisecond)), nil }
+	d, err := time.ParseDuration(v)
+	if err == nil && d < 0 { err = errors.New("negative duration") }
+	return d, err
+}
+
+func limitMessage(msg string) string {
+	msg = strings.TrimSpace(msg)
+	if r := []rune(msg); len(r) > maxPushMessage { msg = string(r[:maxPushMessage]) }
+	return msg
+}
+
+func renderStatusPage(w http.ResponseWriter, title string, sites []models.Site, groupBy string) {
 	sort.Slice(sites, func(i, j int) bool {
 		if sites[i].Status != sites[j].Status {
 			if sites[i].Status == "DOWN" { return true }
@@ -103,6 +289,7 @@ func renderStatusPage(w http.ResponseWriter, title string) {
 		}
 		return sites[i].Name < sites[j].Name
 	})
+	sections := statusSections(sites, groupBy)
 
 	const tpl = `
 	<!DOCTYPE html>
@@ -124,30 +311,91 @@ func renderStatusPage(w http.ResponseWriter, title string) {
 			.DOWN { background: #f7768e; color: #1a1b26; }
 			.PENDING { background: #e0af68; color: #1a1b26; }
 			.SSLEXP { background: #e0af68; color: #1a1b26; }
+			.UNREACHABLE { background: #565f89; color: #1a1b26; }
+			.FLAP { background: #e0af68; color: #1a1b26; }
+			.DEGRADED { background: #e0af68; color: #1a1b26; }
+			.member { margin-left: 40px; padding: 12px 20px; }
+			.section { color: #7aa2f7; font-size: 1em; text-transform: uppercase; letter-spacing: 1px; margin: 30px 0 10px; }
 		</style>
 	</head>
 	<body>
 		<div class="container">
 			<h1>{{.Title}}</h1>
-			{{range .Sites}}
-			<div class="card">
+			{{range .Sections}}
+			{{if .Name}}<h2 class="section">{{.Name}}</h2>{{end}}
+			{{range .Rows}}
+			<div class="card{{if .Member}} member{{end}}">
 				<div class="info">
 					<div class="name">{{.Name}}</div>
-					<div class="meta">{{.Type}} | {{if eq .Type "http"}}{{.URL}}{{else}}Heartbeat Monitor{{end}}</div>
+					<div class="meta">{{.Type}} | {{if eq .Type "push"}}Heartbeat Monitor{{else}}{{.Target}}{{end}}</div>
 					<div class="meta" style="margin-top:4px;">Last Check: {{.LastCheck.Format "15:04:05"}}</div>
 				</div>
 				<div class="status {{.Status}}">{{.Status}}</div>
 			</div>
 			{{end}}
+			{{end}}
 			<div style="text-align: center; margin-top: 40px; color: #565f89; font-size: 0.8em;">Powered by Go-Upkeep</div>
 		</div>
 		<script>
 			setTimeout(function(){ window.location.reload(1); }, 5000);
+			if (window.EventSource) {
+				new EventSource("/status/events").onmessage = function() { setTimeout(function(){ window.location.reload(1); }, 500); };
+			}
 		</script>
 	</body>
 	</html>`
 
 	t, _ := template.New("status").Parse(tpl)
-	data := struct { Title string; Sites []models.Site }{Title: title, Sites: sites}
+	data := struct { Title string; Sections []statusSection }{Title: title, Sections: sections}
 	t.Execute(w, data)
+}
+
+type statusSection struct {
+	Name string
+	Rows []statusRow
+}
+
+// statusSections splits sites by the value of their groupBy tag, sections in
+// name order with untagged sites last. No key gives one unnamed section.
+func statusSections(sites []models.Site, groupBy string) []statusSection {
+	if groupBy == "" { return []statusSection{{Rows: statusRows(sites)}} }
+	byValue := make(map[string][]models.Site)
+	for _, s := range sites { v := s.TagValue(groupBy); byValue[v] = append(byValue[v], s) }
+	var names []string
+	for v := range byValue { if v != "" { names = append(names, v) } }
+	sort.Strings(names)
+	var sections []statusSection
+	for _, v := range names { sections = append(sections, statusSection{Name: v, Rows: statusRows(byValue[v])}) }
+	if other := byValue[""]; len(other) > 0 {
+		name := "Other"; if len(sections) == 0 { name = "" }
+		sections = append(sections, statusSection{Name: name, Rows: statusRows(other)})
+	}
+	return sections
+}
+
+type statusRow struct {
+	models.Site
+	Member bool
+}
+
+// statusRows lists each group followed by its members; sites inside a group
+// are not repeated at the top level.
+func statusRows(sites []models.Site) []statusRow {
+	byID := make(map[int]models.Site)
+	inGroup := make(map[int]bool)
+	for _, s := range sites { byID[s.ID] = s }
+	for _, s := range sites {
+		if s.Type != "group" { continue }
+		for _, mb := range s.Members { if _, ok := byID[mb.ID]; ok && mb.ID != s.ID { inGroup[mb.ID] = true } }
+	}
+	var rows []statusRow
+	for _, s := range sites {
+		if inGroup[s.ID] && s.Type != "group" { continue }
+		rows = append(rows, statusRow{Site: s})
+		if s.Type != "group" { continue }
+		for _, mb := range s.Members {
+			if m, ok := byID[mb.ID]; ok && mb.ID != s.ID { rows = append(rows, statusRow{Site: m, Member: true}) }
+		}
+	}
+	return rows
 }
\ No newline at end of file
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"go-upkeep/internal/models"
+	"go-upkeep/internal/monitor"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cret"
+
+// setLive replaces the engine's sites for a test and restores them afterwards.
+func setLive(t *testing.T, sites ...models.Site) {
+	t.Helper()
+	monitor.Mutex.Lock()
+	old := monitor.LiveState
+	monitor.LiveState = make(map[int]models.Site)
+	for _, s := range sites { monitor.LiveState[s.ID] = s }
+	monitor.Mutex.Unlock()
+	t.Cleanup(func() { monitor.Mutex.Lock(); monitor.LiveState = old; monitor.Mutex.Unlock() })
+}
+
+// call sends a request through the server's routes, with the cluster secret when auth is set.
+func call(method, target string, auth bool) *httptest.ResponseRecorder {
+	return send(httptest.NewRequest(method, target, nil), auth)
+}
+
+func send(req *http.Request, auth bool) *httptest.ResponseRecorder {
+	if auth { req.Header.Set("X-Upkeep-Secret", testSecret) }
+	rec := httptest.NewRecorder()
+	newMux(ServerConfig{Port: 8080, ClusterKey: testSecret}).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	setLive(t, models.Site{ID: 1, Name: "api", Type: "http", URL: up.URL, Status: "PENDING"})
+
+	tests := []struct {
+		name, target string
+		auth         bool
+		want         int
+	}{
+		{"no secret", "/api/sites/1/check", false, 401},
+		{"bad id", "/api/sites/x/check", true, 400},
+		{"unknown site", "/api/sites/9/check", true, 404},
+		{"ok", "/api/sites/1/check", true, 200},
+	}
+	for _, tt := range tests {
+		rec := call("POST", tt.target, tt.auth)
+		if rec.Code != tt.want { t.Errorf("%s: status %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body) }
+	}
+
+	var body struct { ID int; Status string; Code int }
+	if err := json.NewDecoder(call("POST", "/api/sites/1/check", true).Body).Decode(&body); err != nil { t.Fatal(err) }
+	if body.ID != 1 || body.Status != "UP" || body.Code != 200 { t.Errorf("check result %+v", body) }
+	if rec := call("GET", "/api/sites/1/check", true); rec.Code != 405 { t.Errorf("GET check: status %d, want 405", rec.Code) }
+
+	monitor.SetEngineActive(false); defer monitor.SetEngineActive(true)
+	if rec := call("POST", "/api/sites/1/check", true); rec.Code != 503 { t.Errorf("passive engine: status %d, want 503", rec.Code) }
+}
+
+// pushSite puts a push monitor with token "tok" in the engine.
+func pushSite(t *testing.T) {
+	site := models.Site{ID: 1, Name: "backup", Type: "push", Interval: 3600, Status: "UP", MaxRetries: 2, LastHeartbeat: time.Now()}
+	setLive(t, site)
+	site.Token = "tok"; monitor.UpdateSiteConfig(site) // Indexes the token
+	t.Cleanup(func() { site.Token = ""; monitor.UpdateSiteConfig(site) })
+}
+
+func live(id int) models.Site { monitor.Mutex.RLock(); defer monitor.Mutex.RUnlock(); return monitor.LiveState[id] }
+
+func TestPushEndpoint(t *testing.T) {
+	form := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/push/tok", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded"); return req
+	}
+	jsonBody := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/push", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json"); return req
+	}
+	get := func(target string) *http.Request { return httptest.NewRequest("GET", target, nil) }
+	long := strings.Repeat("é", maxPushMessage+20)
+
+	tests := []struct {
+		name    string
+		req     *http.Request
+		code    int
+		status  string
+		message string
+		latency time.Duration
+	}{
+		{"path token", get("/api/push/tok"), 200, "UP", "", 0},
+		{"query token with ping", get("/api/push?token=tok&ping=1500"), 200, "UP", "", 1500 * time.Millisecond},
+		{"form down", form("status=down&msg=disk+full"), 200, "DOWN", "disk full", 0},
+		{"json up", jsonBody(`{"token":"tok","status":"up","msg":"  done  ","duration":"2m"}`), 200, "UP", "done", 2 * time.Minute},
+		{"long message", get("/api/push/tok?msg=" + long), 200, "UP", long[:2*maxPushMessage], 0},
+		{"unknown token", get("/api/push/nope"), 404, "", "", 0},
+		{"missing token", get("/api/push"), 400, "", "", 0},
+		{"bad status", get("/api/push/tok?status=maybe"), 400, "", "", 0},
+		{"bad duration", get("/api/push/tok?duration=-5s"), 400, "", "", 0},
+		{"bad json", jsonBody(`{"token":`), 400, "", "", 0},
+	}
+	for _, tt := range tests {
+		pushSite(t)
+		rec := send(tt.req, false)
+		if rec.Code != tt.code { t.Errorf("%s: status %d, want %d (%s)", tt.name, rec.Code, tt.code, rec.Body); continue }
+		if tt.code != 200 { continue }
+		s := live(1)
+		if s.Status != tt.status || s.LastMessage != tt.message || s.Latency != tt.latency {
+			t.Errorf("%s: site %s %q %s, want %s %q %s", tt.name, s.Status, s.LastMessage, s.Latency, tt.status, tt.message, tt.latency)
+		}
+	}
+}
+
+func TestPushStartMeasuresRuntime(t *testing.T) {
+	pushSite(t)
+	if rec := call("GET", "/api/push/tok?status=start", false); rec.Code != 200 { t.Fatalf("start: status %d", rec.Code) }
+	if live(1).JobStarted.IsZero() { t.Fatal("start push did not record the run") }
+	time.Sleep(20 * time.Millisecond)
+	call("GET", "/api/push/tok", false)
+	if s := live(1); !s.JobStarted.IsZero() || s.Latency < 20*time.Millisecond { t.Errorf("finish: started %v, runtime %s", s.JobStarted, s.Latency) }
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"1500", 1500 * time.Millisecond, true},
+		{"0.5", 500 * time.Microsecond, true},
+		{"1m30s", 90 * time.Second, true},
+		{"-1", 0, false},
+		{"-1s", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) { t.Errorf("parseDuration(%q) = %s, %v", tt.in, got, err) }
+	}
+}
+
+func TestLimitMessage(t *testing.T) {
+	if got := limitMessage("  ok \n"); got != "ok" { t.Errorf("limitMessage trims to %q", got) }
+	// Cut on runes, so multi-byte text stays valid UTF-8
+	if got := []rune(limitMessage(strings.Repeat("日", maxPushMessage+1))); len(got) != maxPushMessage { t.Errorf("limitMessage kept %d runes", len(got)) }
+}
+
+func TestSiteListingHidesTokens(t *testing.T) {
+	setLive(t,
+		models.Site{ID: 1, Name: "backup", Type: "push", Token: "tok", Tags: []string{"env:prod"}},
+		models.Site{ID: 2, Name: "db", Type: "postgres", DSN: "postgres://u:p@db/x", Tags: []string{"env:dev"}},
+	)
+	if rec := call("GET", "/api/sites", false); rec.Code != 401 { t.Errorf("no secret: status %d, want 401", rec.Code) }
+
+	var sites []models.Site
+	rec := call("GET", "/api/sites", true)
+	if err := json.NewDecoder(rec.Body).Decode(&sites); err != nil { t.Fatal(err) }
+	if len(sites) != 2 || sites[0].Token != models.RedactedSecret || sites[1].Token != "" || sites[1].DSN != models.RedactedSecret {
+		t.Errorf("listing leaks secrets: %+v", sites)
+	}
+	if err := json.NewDecoder(call("GET", "/api/sites?tag=env:dev", true).Body).Decode(&sites); err != nil || len(sites) != 1 || sites[0].ID != 2 {
+		t.Errorf("tag filter returned %+v, %v", sites, err)
+	}
+
+	var site models.Site
+	if err := json.NewDecoder(call("GET", "/api/sites/1", true).Body).Decode(&site); err != nil || site.Token != "tok" { t.Errorf("single site token %q, %v", site.Token, err) }
+	if rec := call("GET", "/api/sites/1", false); rec.Code != 401 { t.Errorf("single site without secret: status %d", rec.Code) }
+	if rec := call("GET", "/api/sites/9", true); rec.Code != 404 { t.Errorf("unknown site: status %d, want 404", rec.Code) }
+}
+
+func TestImportRejectsCycles(t *testing.T) {
+	tests := map[string]string{
+		"dependency": `{"Sites":[{"ID":1,"ParentIDs":[2]},{"ID":2,"ParentIDs":[1]}]}`,
+		"group":      `{"Sites":[{"ID":1,"Type":"group","Members":[{"ID":2}]},{"ID":2,"Type":"group","Members":[{"ID":3}]},{"ID":3,"Type":"group","Members":[{"ID":1}]}]}`,
+	}
+	for name, body := range tests {
+		rec := send(httptest.NewRequest("POST", "/api/backup/import", strings.NewReader(body)), true)
+		if rec.Code != 400 || !strings.Contains(rec.Body.String(), "cycle") { t.Errorf("%s cycle: status %d %q, want 400", name, rec.Code, rec.Body) }
+	}
+}
+
+func TestStatusSections(t *testing.T) {
+	sites := []models.Site{
+		{ID: 1, Name: "api", Tags: []string{"team:web"}},
+		{ID: 2, Name: "db", Tags: []string{"team:data"}},
+		{ID: 3, Name: "misc"},
+		{ID: 4, Name: "grp", Type: "group", Tags: []string{"team:web"}, Members: []models.GroupMember{{ID: 1}}},
+	}
+	names := func(sections []statusSection) (out []string) {
+		for _, s := range sections {
+			var rows []string
+			for _, r := range s.Rows { rows = append(rows, r.Name) }
+			out = append(out, s.Name+"="+strings.Join(rows, ","))
+		}
+		return out
+	}
+	if got := strings.Join(names(statusSections(sites, "team")), " "); got != "data=db web=grp,api Other=misc" { t.Errorf("by team: %s", got) }
+	if got := strings.Join(names(statusSections(sites, "")), " "); got != "=db,misc,grp,api" { t.Errorf("ungrouped: %s", got) }
+	if got := strings.Join(names(statusSections(sites[2:3], "team")), " "); got != "=misc" { t.Errorf("no tagged sites: %s", got) }
+}
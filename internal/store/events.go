@@ -0,0 +1,54 @@
+package store
+
+import "sync"
+
+// --- CHANGE EVENTS ---
+// The store announces configuration changes so the engine, TUI sessions and the
+// status server can react immediately instead of polling.
+
+type EventKind string
+
+const (
+	SiteAdded    EventKind = "site_added"
+	SiteUpdated  EventKind = "site_updated"
+	SiteDeleted  EventKind = "site_deleted"
+	AlertChanged EventKind = "alert_changed"
+	Reloaded     EventKind = "reloaded" // Bulk change (import, lost listener); resync everything
+)
+
+type Event struct {
+	Kind EventKind
+	ID   int
+}
+
+const subscriberBuffer = 256
+
+var (
+	subscribers = make(map[int]chan Event)
+	nextSubID   int
+	subMutex    sync.Mutex
+)
+
+// Subscribe returns a channel of change events and a function that closes it.
+func Subscribe() (<-chan Event, func()) {
+	subMutex.Lock(); defer subMutex.Unlock()
+	id := nextSubID; nextSubID++
+	ch := make(chan Event, subscriberBuffer)
+	subscribers[id] = ch
+	return ch, func() {
+		subMutex.Lock(); defer subMutex.Unlock()
+		if c, ok := subscribers[id]; ok { delete(subscribers, id); close(c) }
+	}
+}
+
+// Publish delivers an event to every subscriber. A subscriber whose buffer is
+// full misses the event; the engine's periodic resync covers that case.
+func Publish(e Event) {
+	subMutex.Lock(); defer subMutex.Unlock()
+	for _, ch := range subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package store
+
+import "testing"
+
+func TestParseNotification(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    Event
+		ok      bool
+	}{
+		{"sites:INSERT:7", Event{Kind: SiteAdded, ID: 7}, true},
+		{"sites:UPDATE:7", Event{Kind: SiteUpdated, ID: 7}, true},
+		{"sites:DELETE:7", Event{Kind: SiteDeleted, ID: 7}, true},
+		{"alerts:UPDATE:3", Event{Kind: AlertChanged, ID: 3}, true},
+		{"alerts:DELETE:3", Event{Kind: AlertChanged, ID: 3}, true},
+		{"sites:TRUNCATE:", Event{Kind: Reloaded}, true},
+		{"alerts:TRUNCATE:", Event{Kind: Reloaded}, true},
+		{"sites:MERGE:7", Event{}, false},
+		{"sites:UPDATE", Event{}, false},
+		{"", Event{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseNotification(tt.payload); got != tt.want || ok != tt.ok { t.Errorf("parseNotification(%q) = %+v, %v, want %+v, %v", tt.payload, got, ok, tt.want, tt.ok) }
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	a, unsubA := Subscribe()
+	b, unsubB := Subscribe()
+	defer unsubB()
+	Publish(Event{Kind: SiteUpdated, ID: 1})
+	for name, ch := range map[string]<-chan Event{"a": a, "b": b} {
+		select {
+		case e := <-ch: if e != (Event{Kind: SiteUpdated, ID: 1}) { t.Errorf("%s got %+v", name, e) }
+		default: t.Errorf("%s got nothing", name)
+		}
+	}
+
+	unsubA(); unsubA() // Closing twice is harmless
+	if _, open := <-a; open { t.Error("unsubscribed channel still open") }
+	Publish(Event{Kind: SiteDeleted, ID: 1}) // Must not send on the closed channel
+	<-b
+
+	// A full subscriber misses events instead of blocking the publisher
+	for i := range subscriberBuffer + 10 { Publish(Event{Kind: SiteAdded, ID: i}) }
+	if len(b) != subscriberBuffer { t.Errorf("buffer holds %d events, want %d", len(b), subscriberBuffer) }
+	if e := <-b; e.ID != 0 { t.Errorf("first buffered event %+v, want ID 0", e) }
+}
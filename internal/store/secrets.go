@@ -0,0 +1,146 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"go-upkeep/internal/models"
+	"os"
+	"strings"
+	"sync"
+)
+
+// --- SECRETS ---
+// Monitor credentials are sealed with AES-GCM before they reach the database and
+// opened again when sites are read. Exports carry the sealed form, so a backup
+// only restores on a node configured with the same key.
+
+// SecretKey is a passphrase for the encryption key; when empty the key is read
+// from SecretKeyFile, which is generated on first use. Losing the file makes the
+// stored credentials unreadable, so it belongs on persistent storage. Set before Init.
+var (
+	SecretKey     string
+	SecretKeyFile = "upkeep.key"
+)
+
+// sealedPrefix marks sealed values; legacyPrefix is the unversioned form written by earlier releases.
+const (
+	sealedPrefix = "enc:v1:"
+	legacyPrefix = "enc:"
+)
+
+var (
+	aead     cipher.AEAD
+	aeadErr  error
+	aeadOnce sync.Once
+	warnOnce sync.Once
+)
+
+// LoadSecretKey prepares the cipher, creating the key file if needed.
+func LoadSecretKey() error {
+	aeadOnce.Do(func() {
+		var key []byte
+		if SecretKey != "" {
+			sum := sha256.Sum256([]byte(SecretKey)); key = sum[:]
+		} else if key, aeadErr = readKeyFile(SecretKeyFile); aeadErr != nil {
+			return
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil { aeadErr = err; return }
+		aead, aeadErr = cipher.NewGCM(block)
+	})
+	return aeadErr
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	if raw, err := os.ReadFile(path); err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+		if err != nil || len(key) != 32 { return nil, errors.New("invalid secret key file " + path) }
+		return key, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil { return nil, err }
+	return key, os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600)
+}
+
+// sealedPayload returns the ciphertext of a sealed value. Values that merely
+// start with a prefix but do not decode to nonce, data and tag are not sealed.
+func sealedPayload(v string) ([]byte, bool) {
+	rest, ok := strings.CutPrefix(v, sealedPrefix)
+	if !ok { rest, ok = strings.CutPrefix(v, legacyPrefix) }
+	if !ok { return nil, false }
+	raw, err := base64.StdEncoding.DecodeString(rest)
+	if err != nil || LoadSecretKey() != nil || len(raw) < aead.NonceSize()+aead.Overhead() { return nil, false }
+	return raw, true
+}
+
+// seal encrypts a value; empty and already sealed values pass through unchanged.
+func seal(plain string) string {
+	if _, ok := sealedPayload(plain); plain == "" || ok || LoadSecretKey() != nil { return plain }
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plain), nil))
+}
+
+var errUnseal = errors.New("cannot decrypt stored credential")
+
+// unseal decrypts a sealed value. On failure (usually a different key) it
+// returns the sealed value unchanged, so saving the site again keeps it intact.
+func unseal(sealed string) (string, error) {
+	raw, ok := sealedPayload(sealed)
+	if !ok { return sealed, nil }
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil { return sealed, errUnseal }
+	return string(plain), nil
+}
+
+func sealSite(st models.Site) models.Site {
+	st.AuthPass, st.AuthToken, st.ClientKey, st.DSN = seal(st.AuthPass), seal(st.AuthToken), seal(st.ClientKey), seal(st.DSN)
+	st.ProxyURL = seal(st.ProxyURL) // May carry proxy credentials
+	st.Steps = mapStepSecrets(st.Steps, seal)
+	return st
+}
+
+func unsealSite(st models.Site) models.Site {
+	failed := false
+	open := func(v string) string {
+		plain, err := unseal(v)
+		if err != nil { failed = true }
+		return plain
+	}
+	st.AuthPass, st.AuthToken, st.ClientKey, st.DSN = open(st.AuthPass), open(st.AuthToken), open(st.ClientKey), open(st.DSN)
+	st.ProxyURL = open(st.ProxyURL)
+	st.Steps = mapStepSecrets(st.Steps, open)
+	if failed {
+		warnOnce.Do(func() { Log("Secrets: some monitor credentials could not be decrypted; check UPKEEP_SECRET_KEY or the key file") })
+	}
+	return st
+}
+
+// mapStepSecrets applies fn to transaction step bodies and header values, copying the slice.
+func mapStepSecrets(steps []models.Step, fn func(string) string) []models.Step {
+	if steps == nil { return nil }
+	out := make([]models.Step, len(steps))
+	for i, st := range steps {
+		st.Body = fn(st.Body)
+		if st.Headers != nil {
+			h := make(map[string]string, len(st.Headers))
+			for k, v := range st.Headers { h[k] = fn(v) }
+			st.Headers = h
+		}
+		out[i] = st
+	}
+	return out
+}
+
+// sealedSites prepares sites for export.
+func sealedSites(sites []models.Site) []models.Site {
+	for i := range sites { sites[i] = sealSite(sites[i]) }
+	return sites
+}
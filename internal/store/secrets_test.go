@@ -0,0 +1,44 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"go-upkeep/internal/models"
+	"testing"
+)
+
+// useKey swaps the sealing key for a test.
+func useKey(t *testing.T, pass string) {
+	t.Helper()
+	LoadSecretKey()
+	prev := aead
+	sum := sha256.Sum256([]byte(pass))
+	block, _ := aes.NewCipher(sum[:])
+	aead, _ = cipher.NewGCM(block)
+	t.Cleanup(func() { aead = prev })
+}
+
+func TestUnsealWrongKeyKeepsCiphertext(t *testing.T) {
+	SecretKey = "test"
+	useKey(t, "first")
+	sealed := sealSite(models.Site{AuthPass: "hunter2"})
+	if got := unsealSite(sealed).AuthPass; got != "hunter2" { t.Fatalf("round trip = %q", got) }
+
+	useKey(t, "second")
+	got := unsealSite(sealed)
+	if got.AuthPass != sealed.AuthPass { t.Errorf("wrong key: AuthPass = %q, want the sealed value", got.AuthPass) }
+	if again := sealSite(got); again.AuthPass != sealed.AuthPass { t.Errorf("saving again changed the sealed value") }
+}
+
+func TestSealPrefixedPlaintext(t *testing.T) {
+	SecretKey = "test"
+	useKey(t, "first")
+	for _, plain := range []string{"enc:hunter2", "enc:v1:hunter2", "enc:v1:", "plain"} {
+		sealed := seal(plain)
+		if sealed == plain { t.Errorf("seal(%q) stored the value unsealed", plain) }
+		if got, err := unseal(sealed); got != plain || err != nil { t.Errorf("unseal(seal(%q)) = %q, %v", plain, got, err) }
+	}
+	// Written unsealed by earlier releases: read back as plaintext
+	if got, err := unseal("enc:hunter2"); got != "enc:hunter2" || err != nil { t.Errorf("unseal(legacy plaintext) = %q, %v", got, err) }
+}
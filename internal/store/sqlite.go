@@ -44,11 +44,67 @@ func (s *SQLiteStore) Init() error {
 		username TEXT NOT NULL,
 		public_key TEXT NOT NULL,
 		role TEXT DEFAULT 'user'
-	);`
-	_, err = s.db.Exec(createTables)
-	return err
+	);
+	CREATE TABLE IF NOT EXISTS site_state (
+		site_id INTEGER PRIMARY KEY,
+		status TEXT,
+		failure_count INTEGER DEFAULT 0,
+		last_check TIMESTAMP,
+		last_heartbeat TIMESTAMP,
+		sent_ssl_warning BOOLEAN DEFAULT 0
+	);
+	CREATE TABLE IF NOT EXISTS site_tags (
+		site_id INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (site_id, tag)
+	);
+	CREATE INDEX IF NOT EXISTS site_tags_tag ON site_tags (tag);`
+	if _, err = s.db.Exec(createTables); err != nil { return err }
+
+	// Columns added after the initial schema. SQLite has no ADD COLUMN IF NOT EXISTS,
+	// so errors from already-migrated databases are ignored.
+	migrations := []string{
+		"ALTER TABLE sites ADD COLUMN parent_ids TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN latency_warn INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN latency_crit INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN degraded_after INTEGER DEFAULT 1",
+		"ALTER TABLE sites ADD COLUMN retry_interval INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN timeout INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN redirect_policy TEXT DEFAULT 'follow'",
+		"ALTER TABLE sites ADD COLUMN max_redirects INTEGER DEFAULT 10",
+		"ALTER TABLE sites ADD COLUMN proxy_url TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN auth_user TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN auth_pass TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN auth_token TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN client_cert TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN client_key TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN cron TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN timezone TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN grace_period INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN max_runtime INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN packet_count INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN loss_warn INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN loss_crit INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN dsn TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN query TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN expect TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN service TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN payload TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN steps TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN members TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN group_rule TEXT DEFAULT ''",
+		"ALTER TABLE sites ADD COLUMN group_min INTEGER DEFAULT 0",
+		"ALTER TABLE sites ADD COLUMN mute_members BOOLEAN DEFAULT 0",
+		"ALTER TABLE site_state ADD COLUMN last_message TEXT DEFAULT ''",
+		"ALTER TABLE site_state ADD COLUMN push_failed BOOLEAN DEFAULT 0",
+		"ALTER TABLE site_state ADD COLUMN job_started TIMESTAMP",
+	}
+	for _, q := range migrations { s.db.Exec(q) }
+	return nil
 }
 
+func sqliteBind(int) string { return "?" }
+
 func generateToken() string {
 	b := make([]byte, 16)
 	rand.Read(b)
@@ -56,33 +112,69 @@ func generateToken() string {
 }
 
 func (s *SQLiteStore) GetSites() []models.Site {
-	rows, err := s.db.Query("SELECT id, COALESCE(name, url), url, COALESCE(type, 'http'), COALESCE(token, ''), interval, alert_id, check_ssl, threshold, max_retries FROM sites")
+	rows, err := s.db.Query(siteSelect)
 	if err != nil { return []models.Site{} }
 	defer rows.Close()
 	var sites []models.Site
 	for rows.Next() {
-		var st models.Site
-		rows.Scan(&st.ID, &st.Name, &st.URL, &st.Type, &st.Token, &st.Interval, &st.AlertID, &st.CheckSSL, &st.ExpiryThreshold, &st.MaxRetries)
+		st, _ := scanSite(rows)
 		sites = append(sites, st)
 	}
-	return sites
+	return withTags(sites, loadTags(s.db, sqliteBind, 0))
 }
-func (s *SQLiteStore) AddSite(name, url, sType string, interval, alertID int, checkSSL bool, threshold, retries int) {
-	token := ""
-	if sType == "push" { token = generateToken() }
-	s.db.Exec("INSERT INTO sites (name, url, type, token, interval, alert_id, check_ssl, threshold, max_retries) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", name, url, sType, token, interval, alertID, checkSSL, threshold, retries)
+func (s *SQLiteStore) GetSite(id int) (models.Site, bool) {
+	st, err := scanSite(s.db.QueryRow(siteSelect+" WHERE id=?", id))
+	st.Tags = loadTags(s.db, sqliteBind, id)[id]
+	return st, err == nil
 }
-func (s *SQLiteStore) UpdateSite(id int, name, url, sType string, interval, alertID int, checkSSL bool, threshold, retries int) {
-	var existingToken string
-	s.db.QueryRow("SELECT token FROM sites WHERE id=?", id).Scan(&existingToken)
-	if sType == "push" && existingToken == "" { existingToken = generateToken() }
-	s.db.Exec("UPDATE sites SET name=?, url=?, type=?, token=?, interval=?, alert_id=?, check_ssl=?, threshold=?, max_retries=? WHERE id=?", name, url, sType, existingToken, interval, alertID, checkSSL, threshold, retries, id)
+func (s *SQLiteStore) AddSite(site models.Site) int {
+	site.Token = ""
+	if site.Type == "push" { site.Token = generateToken() }
+	tx, err := s.db.Begin()
+	if err != nil { return 0 }
+	res, err := tx.Exec(insertSiteSQL(false, sqliteBind), siteArgs(site)...)
+	if err != nil { tx.Rollback(); return 0 }
+	id, _ := res.LastInsertId()
+	if err := saveTags(tx, sqliteBind, int(id), site.Tags); err != nil { tx.Rollback(); return 0 }
+	if tx.Commit() != nil { return 0 }
+	Publish(Event{Kind: SiteAdded, ID: int(id)})
+	return int(id)
+}
+func (s *SQLiteStore) UpdateSite(site models.Site) {
+	s.db.QueryRow("SELECT COALESCE(token, '') FROM sites WHERE id=?", site.ID).Scan(&site.Token)
+	if site.Type == "push" && site.Token == "" { site.Token = generateToken() }
+	tx, err := s.db.Begin()
+	if err != nil { return }
+	if _, err := tx.Exec(updateSiteSQL(sqliteBind), append(siteArgs(site), site.ID)...); err != nil { tx.Rollback(); return }
+	if saveTags(tx, sqliteBind, site.ID, site.Tags) != nil { tx.Rollback(); return }
+	if tx.Commit() != nil { return }
+	Publish(Event{Kind: SiteUpdated, ID: site.ID})
 }
 func (s *SQLiteStore) DeleteSite(id int) {
 	s.db.Exec("DELETE FROM sites WHERE id=?", id)
+	s.db.Exec("DELETE FROM site_state WHERE site_id=?", id)
+	s.db.Exec("DELETE FROM site_tags WHERE site_id=?", id)
 	var count int
 	s.db.QueryRow("SELECT COUNT(*) FROM sites").Scan(&count)
 	if count == 0 { s.db.Exec("DELETE FROM sqlite_sequence WHERE name='sites'") }
+	Publish(Event{Kind: SiteDeleted, ID: id})
+}
+func (s *SQLiteStore) RotateToken(id int) (string, bool) {
+	token := generateToken()
+	res, err := s.db.Exec("UPDATE sites SET token=? WHERE id=? AND type='push'", token, id)
+	if err != nil { return "", false }
+	if n, _ := res.RowsAffected(); n == 0 { return "", false }
+	Publish(Event{Kind: SiteUpdated, ID: id})
+	return token, true
+}
+func (s *SQLiteStore) GetSiteStates() map[int]models.SiteState {
+	rows, err := s.db.Query(siteStateSelect)
+	if err != nil { return map[int]models.SiteState{} }
+	defer rows.Close()
+	return scanSiteStates(rows)
+}
+func (s *SQLiteStore) SaveSiteState(state models.SiteState) {
+	s.db.Exec(upsertSiteStateSQL(sqliteBind), siteStateArgs(state)...)
 }
 func (s *SQLiteStore) GetAllAlerts() []models.AlertConfig {
 	rows, err := s.db.Query("SELECT id, name, type, settings FROM alerts")
@@ -106,17 +198,22 @@ func (s *SQLiteStore) GetAlert(id int) (models.AlertConfig, bool) {
 }
 func (s *SQLiteStore) AddAlert(name, aType string, settings map[string]string) {
 	jsonBytes, _ := json.Marshal(settings)
-	s.db.Exec("INSERT INTO alerts (name, type, settings) VALUES (?, ?, ?)", name, aType, string(jsonBytes))
+	res, err := s.db.Exec("INSERT INTO alerts (name, type, settings) VALUES (?, ?, ?)", name, aType, string(jsonBytes))
+	if err != nil { return }
+	id, _ := res.LastInsertId()
+	Publish(Event{Kind: AlertChanged, ID: int(id)})
 }
 func (s *SQLiteStore) UpdateAlert(id int, name, aType string, settings map[string]string) {
 	jsonBytes, _ := json.Marshal(settings)
 	s.db.Exec("UPDATE alerts SET name=?, type=?, settings=? WHERE id=?", name, aType, string(jsonBytes), id)
+	Publish(Event{Kind: AlertChanged, ID: id})
 }
 func (s *SQLiteStore) DeleteAlert(id int) {
 	s.db.Exec("DELETE FROM alerts WHERE id=?", id)
 	var count int
 	s.db.QueryRow("SELECT COUNT(*) FROM alerts").Scan(&count)
 	if count == 0 { s.db.Exec("DELETE FROM sqlite_sequence WHERE name='alerts'") }
+	Publish(Event{Kind: AlertChanged, ID: id})
 }
 func (s *SQLiteStore) GetAllUsers() []models.User {
 	rows, err := s.db.Query("SELECT id, username, public_key, role FROM users")
@@ -143,7 +240,7 @@ func (s *SQLiteStore) DeleteUser(id int) error {
 
 func (s *SQLiteStore) ExportData() models.Backup {
 	return models.Backup{
-		Sites:  s.GetSites(),
+		Sites:  sealedSites(s.GetSites()),
 		Alerts: s.GetAllAlerts(),
 		Users:  s.GetAllUsers(),
 	}
@@ -154,7 +251,7 @@ func (s *SQLiteStore) ImportData(data models.Backup) error {
 	if err != nil { return err }
 
 	// Wipe Existing
-	tx.Exec("DELETE FROM sites"); tx.Exec("DELETE FROM sqlite_sequence WHERE name='sites'")
+	tx.Exec("DELETE FROM sites"); tx.Exec("DELETE FROM sqlite_sequence WHERE name='sites'"); tx.Exec("DELETE FROM site_state"); tx.Exec("DELETE FROM site_tags")
 	tx.Exec("DELETE FROM alerts"); tx.Exec("DELETE FROM sqlite_sequence WHERE name='alerts'")
 	tx.Exec("DELETE FROM users"); tx.Exec("DELETE FROM sqlite_sequence WHERE name='users'")
 
@@ -167,9 +264,11 @@ func (s *SQLiteStore) ImportData(data models.Backup) error {
 		tx.Exec("INSERT INTO alerts (id, name, type, settings) VALUES (?, ?, ?, ?)", a.ID, a.Name, a.Type, string(jsonBytes))
 	}
 	for _, st := range data.Sites {
-		tx.Exec("INSERT INTO sites (id, name, url, type, token, interval, alert_id, check_ssl, threshold, max_retries) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
-			st.ID, st.Name, st.URL, st.Type, st.Token, st.Interval, st.AlertID, st.CheckSSL, st.ExpiryThreshold, st.MaxRetries)
+		tx.Exec(insertSiteSQL(true, sqliteBind), append([]any{st.ID}, siteArgs(st)...)...)
+		saveTags(tx, sqliteBind, st.ID, st.Tags)
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil { return err }
+	Publish(Event{Kind: Reloaded})
+	return nil
 }
\ No newline at end of file
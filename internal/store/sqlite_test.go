@@ -0,0 +1,57 @@
+package store
+
+import (
+	"go-upkeep/internal/models"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newSQLite(t *testing.T) *SQLiteStore {
+	SecretKey = "test"
+	s := &SQLiteStore{DBPath: filepath.Join(t.TempDir(), "upkeep.db")}
+	if err := s.Init(); err != nil { t.Fatal(err) }
+	t.Cleanup(func() { s.db.Close() })
+	return s
+}
+
+func tagRows(t *testing.T, s *SQLiteStore, id int) int {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM site_tags WHERE site_id=?", id).Scan(&n); err != nil { t.Fatal(err) }
+	return n
+}
+
+func TestSQLiteSiteTags(t *testing.T) {
+	s := newSQLite(t)
+	id := s.AddSite(models.Site{Name: "api", Type: "http", URL: "https://a.example", Interval: 60, Tags: []string{"Env:Prod", "team:core", "env:prod"}})
+	other := s.AddSite(models.Site{Name: "db", Type: "http", URL: "https://b.example", Interval: 60, Tags: []string{"env:dev"}})
+
+	site, ok := s.GetSite(id)
+	if !ok || !slices.Equal(site.Tags, []string{"env:prod", "team:core"}) { t.Fatalf("saved tags %v (found %v), want normalised", site.Tags, ok) }
+
+	site.Tags = []string{"team:core", "tier:1"}
+	s.UpdateSite(site)
+	sites := s.GetSites()
+	byID := make(map[int]models.Site)
+	for _, st := range sites { byID[st.ID] = st }
+	if got := byID[id].Tags; !slices.Equal(got, []string{"team:core", "tier:1"}) { t.Errorf("updated tags %v", got) }
+	if got := byID[other].Tags; !slices.Equal(got, []string{"env:dev"}) { t.Errorf("other site's tags %v", got) }
+	if n := tagRows(t, s, id); n != 2 { t.Errorf("%d tag rows after update, want 2", n) }
+
+	s.DeleteSite(id)
+	if _, ok := s.GetSite(id); ok { t.Error("deleted site still loads") }
+	if n := tagRows(t, s, id); n != 0 { t.Errorf("%d tag rows left after delete", n) }
+	if n := tagRows(t, s, other); n != 1 { t.Errorf("delete removed other site's tags: %d rows", n) }
+}
+
+func TestSplitTags(t *testing.T) {
+	tests := map[string][]string{
+		"":                               nil,
+		"env:prod":                       {"env:prod"},
+		" Team:Core, env:prod\tenv:prod": {"env:prod", "team:core"},
+		",,a  b,":                        {"a", "b"},
+	}
+	for in, want := range tests {
+		if got := SplitTags(in); !slices.Equal(got, want) { t.Errorf("SplitTags(%q) = %v, want %v", in, got, want) }
+	}
+}
@@ -1,7 +1,14 @@
 package store
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"go-upkeep/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
 type Store interface {
@@ -9,9 +16,15 @@ type Store interface {
 	
 	// Sites
 	GetSites() []models.Site
-	AddSite(name, url, sType string, interval, alertID int, checkSSL bool, threshold, retries int)
-	UpdateSite(id int, name, url, sType string, interval, alertID int, checkSSL bool, threshold, retries int)
+	GetSite(id int) (models.Site, bool)
+	AddSite(site models.Site) int
+	UpdateSite(site models.Site)
 	DeleteSite(id int)
+	RotateToken(id int) (string, bool) // Issues a new push token; false if the site is not a push monitor
+
+	// Live state persisted across restarts
+	GetSiteStates() map[int]models.SiteState
+	SaveSiteState(state models.SiteState)
 
 	// Alerts
 	GetAllAlerts() []models.AlertConfig
@@ -32,10 +45,182 @@ type Store interface {
 
 var Current Store
 
+// Log receives warnings from background store work. Set before Init.
+var Log = func(msg string) { fmt.Println(msg) }
+
 func SetGlobal(s Store) {
 	Current = s
 }
 
 func Get() Store {
 	return Current
-}
\ No newline at end of file
+}
+
+// --- SHARED SITE MAPPING ---
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+const siteSelect = "SELECT id, COALESCE(name, url), url, COALESCE(type, 'http'), COALESCE(token, ''), interval, alert_id, check_ssl, threshold, max_retries, COALESCE(parent_ids, ''), latency_warn, latency_crit, degraded_after, retry_interval, timeout, COALESCE(redirect_policy, 'follow'), max_redirects, COALESCE(proxy_url, ''), COALESCE(auth_user, ''), COALESCE(auth_pass, ''), COALESCE(auth_token, ''), COALESCE(client_cert, ''), COALESCE(client_key, ''), COALESCE(cron, ''), COALESCE(timezone, ''), grace_period, max_runtime, packet_count, loss_warn, loss_crit, COALESCE(dsn, ''), COALESCE(query, ''), COALESCE(expect, ''), COALESCE(service, ''), COALESCE(payload, ''), COALESCE(steps, ''), COALESCE(members, ''), COALESCE(group_rule, ''), group_min, mute_members FROM sites"
+
+// siteFields lists the writable site columns in the order siteArgs returns them.
+var siteFields = []string{"name", "url", "type", "token", "interval", "alert_id", "check_ssl", "threshold", "max_retries", "parent_ids", "latency_warn", "latency_crit", "degraded_after", "retry_interval", "timeout", "redirect_policy", "max_redirects", "proxy_url", "auth_user", "auth_pass", "auth_token", "client_cert", "client_key", "cron", "timezone", "grace_period", "max_runtime", "packet_count", "loss_warn", "loss_crit", "dsn", "query", "expect", "service", "payload", "steps", "members", "group_rule", "group_min", "mute_members"}
+
+func scanSite(r rowScanner) (models.Site, error) {
+	var st models.Site; var parents, steps, members string
+	err := r.Scan(&st.ID, &st.Name, &st.URL, &st.Type, &st.Token, &st.Interval, &st.AlertID, &st.CheckSSL, &st.ExpiryThreshold, &st.MaxRetries, &parents, &st.LatencyWarn, &st.LatencyCrit, &st.DegradedAfter, &st.RetryInterval, &st.Timeout, &st.RedirectPolicy, &st.MaxRedirects, &st.ProxyURL, &st.AuthUser, &st.AuthPass, &st.AuthToken, &st.ClientCert, &st.ClientKey, &st.Cron, &st.Timezone, &st.GracePeriod, &st.MaxRuntime, &st.PacketCount, &st.LossWarn, &st.LossCrit, &st.DSN, &st.Query, &st.Expect, &st.Service, &st.Payload, &steps, &members, &st.GroupRule, &st.GroupMin, &st.MuteMembers)
+	st.Members = SplitMembers(members)
+	st.ParentIDs = SplitIDs(parents)
+	if steps != "" { json.Unmarshal([]byte(steps), &st.Steps) }
+	return unsealSite(st), err
+}
+
+// siteArgs returns the column values for siteFields, with secrets sealed.
+func siteArgs(st models.Site) []any {
+	st = sealSite(st)
+	return []any{st.Name, st.URL, st.Type, st.Token, st.Interval, st.AlertID, st.CheckSSL, st.ExpiryThreshold, st.MaxRetries, JoinIDs(st.ParentIDs), st.LatencyWarn, st.LatencyCrit, st.DegradedAfter, st.RetryInterval, st.Timeout, st.RedirectPolicy, st.MaxRedirects, st.ProxyURL, st.AuthUser, st.AuthPass, st.AuthToken, st.ClientCert, st.ClientKey, st.Cron, st.Timezone, st.GracePeriod, st.MaxRuntime, st.PacketCount, st.LossWarn, st.LossCrit, st.DSN, st.Query, st.Expect, st.Service, st.Payload, encodeSteps(st.Steps), JoinMembers(st.Members), st.GroupRule, st.GroupMin, st.MuteMembers}
+}
+
+// encodeSteps stores transaction steps as JSON; empty for other monitor types.
+func encodeSteps(steps []models.Step) string {
+	if len(steps) == 0 { return "" }
+	b, _ := json.Marshal(steps)
+	return string(b)
+}
+
+// insertSiteSQL builds the INSERT for sites; bind renders the n-th (1-based) placeholder.
+func insertSiteSQL(withID bool, bind func(n int) string) string {
+	cols := siteFields
+	if withID { cols = append([]string{"id"}, siteFields...) }
+	var ph []string
+	for i := range cols { ph = append(ph, bind(i+1)) }
+	return "INSERT INTO sites (" + strings.Join(cols, ", ") + ") VALUES (" + strings.Join(ph, ", ") + ")"
+}
+
+// updateSiteSQL builds the UPDATE for sites; the id is bound last.
+func updateSiteSQL(bind func(n int) string) string {
+	var sets []string
+	for i, c := range siteFields { sets = append(sets, c+"="+bind(i+1)) }
+	return "UPDATE sites SET " + strings.Join(sets, ", ") + " WHERE id=" + bind(len(siteFields)+1)
+}
+
+const siteStateSelect = "SELECT site_id, status, failure_count, last_check, last_heartbeat, sent_ssl_warning, COALESCE(last_message, ''), push_failed, job_started FROM site_state"
+
+// upsertSiteStateSQL writes one site_state row; bind renders the n-th (1-based) placeholder.
+func upsertSiteStateSQL(bind func(n int) string) string {
+	return "INSERT INTO site_state (site_id, status, failure_count, last_check, last_heartbeat, sent_ssl_warning, last_message, push_failed, job_started) VALUES (" +
+		bind(1) + ", " + bind(2) + ", " + bind(3) + ", " + bind(4) + ", " + bind(5) + ", " + bind(6) + ", " + bind(7) + ", " + bind(8) + ", " + bind(9) + ") " +
+		"ON CONFLICT (site_id) DO UPDATE SET status=excluded.status, failure_count=excluded.failure_count, last_check=excluded.last_check, " +
+		"last_heartbeat=excluded.last_heartbeat, sent_ssl_warning=excluded.sent_ssl_warning, last_message=excluded.last_message, push_failed=excluded.push_failed, job_started=excluded.job_started"
+}
+
+func siteStateArgs(st models.SiteState) []any {
+	return []any{st.SiteID, st.Status, st.FailureCount, st.LastCheck, st.LastHeartbeat, st.SentSSLWarning, st.LastMessage, st.PushFailed, st.JobStarted}
+}
+
+func scanSiteStates(rows *sql.Rows) map[int]models.SiteState {
+	states := make(map[int]models.SiteState)
+	for rows.Next() {
+		var st models.SiteState; var started sql.NullTime // NULL in rows written before job_started existed
+		if err := rows.Scan(&st.SiteID, &st.Status, &st.FailureCount, &st.LastCheck, &st.LastHeartbeat, &st.SentSSLWarning, &st.LastMessage, &st.PushFailed, &started); err == nil {
+			st.JobStarted = started.Time
+			states[st.SiteID] = st
+		}
+	}
+	return states
+}
+
+// JoinIDs renders IDs as the comma-separated form used in the database and forms.
+func JoinIDs(ids []int) string {
+	var parts []string
+	for _, id := range ids { parts = append(parts, strconv.Itoa(id)) }
+	return strings.Join(parts, ",")
+}
+
+// JoinMembers renders group members as "id" or "id:weight", comma-separated.
+func JoinMembers(members []models.GroupMember) string {
+	var parts []string
+	for _, m := range members {
+		p := strconv.Itoa(m.ID)
+		if m.Weight > 0 { p += ":" + strconv.Itoa(m.Weight) }
+		parts = append(parts, p)
+	}
+	return strings.Join(parts, ",")
+}
+
+// SplitMembers parses the JoinMembers form, skipping invalid entries.
+func SplitMembers(s string) []models.GroupMember {
+	var members []models.GroupMember
+	for _, p := range strings.Split(s, ",") {
+		idStr, weightStr, hasWeight := strings.Cut(strings.TrimSpace(p), ":")
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil { continue }
+		m := models.GroupMember{ID: id}
+		if hasWeight {
+			if w, err := strconv.Atoi(strings.TrimSpace(weightStr)); err == nil && w > 0 { m.Weight = w }
+		}
+		members = append(members, m)
+	}
+	return members
+}
+
+// JoinTags renders tags the way the site form shows them.
+func JoinTags(tags []string) string { return strings.Join(tags, ", ") }
+
+// SplitTags parses a comma- or space-separated tag list into lowercased,
+// sorted, unique tags.
+func SplitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		if !slices.Contains(tags, t) { tags = append(tags, t) }
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// SplitIDs parses a comma-separated ID list, skipping invalid entries.
+func SplitIDs(s string) []int {
+	var ids []int
+	for _, p := range strings.Split(s, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(p)); err == nil { ids = append(ids, id) }
+	}
+	return ids
+}
+
+// --- TAGS ---
+// Tags live in the site_tags join table, one row per tag, and are attached to
+// sites after the main query. Writes replace a site's whole tag set.
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// loadTags returns tags by site ID, for every site or only the given one (id > 0).
+func loadTags(db *sql.DB, bind func(n int) string, id int) map[int][]string {
+	q := "SELECT site_id, tag FROM site_tags"; var args []any
+	if id > 0 { q += " WHERE site_id=" + bind(1); args = append(args, id) }
+	tags := make(map[int][]string)
+	rows, err := db.Query(q+" ORDER BY tag", args...)
+	if err != nil { return tags }
+	defer rows.Close()
+	for rows.Next() {
+		var siteID int; var tag string
+		if rows.Scan(&siteID, &tag) == nil { tags[siteID] = append(tags[siteID], tag) }
+	}
+	return tags
+}
+
+func withTags(sites []models.Site, tags map[int][]string) []models.Site {
+	for i := range sites { sites[i].Tags = tags[sites[i].ID] }
+	return sites
+}
+
+// saveTags replaces the tags of a site.
+func saveTags(x execer, bind func(n int) string, id int, tags []string) error {
+	if _, err := x.Exec("DELETE FROM site_tags WHERE site_id="+bind(1), id); err != nil { return err }
+	for _, t := range SplitTags(strings.Join(tags, ",")) {
+		if _, err := x.Exec("INSERT INTO site_tags (site_id, tag) VALUES ("+bind(1)+", "+bind(2)+")", id, t); err != nil { return err }
+	}
+	return nil
+}
@@ -5,12 +5,14 @@ import (
 	"go-upkeep/internal/models"
 	"go-upkeep/internal/monitor"
 	"go-upkeep/internal/store" 
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -22,6 +24,7 @@ var (
 	specialStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"})
 	warnStyle    = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#F0E442", Dark: "#F0E442"})
 	dangerStyle  = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#F25D94"})
+	mutedStyle   = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#999999", Dark: "#888888"})
 	titleStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#7D56F4")).Bold(true)
 
 	activeTab   = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, true, false).BorderForeground(lipgloss.Color("#7D56F4")).Foreground(lipgloss.Color("#7D56F4")).Bold(true).Padding(0, 1)
@@ -30,7 +33,7 @@ var (
 	colID      = lipgloss.NewStyle().Width(4)
 	colName    = lipgloss.NewStyle().Width(15)
 	colURL     = lipgloss.NewStyle().Width(25)
-	colStatus  = lipgloss.NewStyle().Width(8)
+	colStatus  = lipgloss.NewStyle().Width(12)
 	colSSL     = lipgloss.NewStyle().Width(10)
 	colType    = lipgloss.NewStyle().Width(6)
 	colRetries = lipgloss.NewStyle().Width(6)
@@ -56,8 +59,17 @@ const (
 	stateFormAlert
 	stateFormUser
 	stateSelectAlert
+	stateSiteDetail
+	stateEditSteps
 )
 
+// checkDoneMsg carries the outcome of an on-demand check back to the UI.
+type checkDoneMsg struct {
+	id  int
+	res monitor.CheckResult
+	err error
+}
+
 type Model struct {
 	state      sessionState
 	currentTab int
@@ -78,11 +90,28 @@ type Model struct {
 	logViewport  viewport.Model
 	formViewport viewport.Model
 	alertList    list.Model
+	stepsEditor  textarea.Model
 	creatingAlertFromSite bool
 	isAdmin bool 
 
-	sites  []models.Site
-	alerts []models.AlertConfig
+	detailID      int
+	checking      bool
+	checkResult   *checkDoneMsg
+	confirmRotate bool   // First [r] press in the detail view; the second one rotates
+	detailNotice  string
+
+	filterInput textinput.Model
+	filtering   bool     // Typing a tag filter on the Sites tab
+	tagFilter   []string // Tag selectors every listed site must match
+	siteTotal   int      // Sites before the filter is applied
+
+	events      <-chan store.Event
+	unsubscribe func()
+
+	sites     []models.Site
+	siteDepth []int         // Tree depth of each row in sites
+	siteAlso  map[int][]int // Further parents of sites listed under their first one
+	alerts    []models.AlertConfig
 	users  []models.User
 }
 
@@ -93,25 +122,27 @@ func InitialModel(isAdmin bool) Model {
 	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	l.Title = "Select Alert Config"
 	l.SetShowHelp(false)
-	return Model{state: stateDashboard, logViewport: vpLogs, formViewport: vpForm, alertList: l, maxTableRows: 5, currentAlertType: "discord", isAdmin: isAdmin}
+	steps := textarea.New(); steps.ShowLineNumbers = true; steps.CharLimit = 0
+	filter := ti("env:prod team:payments", 40); filter.Prompt = "Filter by tag: "
+	events, unsubscribe := store.Subscribe()
+	return Model{state: stateDashboard, logViewport: vpLogs, formViewport: vpForm, alertList: l, stepsEditor: steps, filterInput: filter, maxTableRows: 5, currentAlertType: "discord", isAdmin: isAdmin, events: events, unsubscribe: unsubscribe}
 }
 
+// Close stops the session's store subscription. Call it once the program exits.
+func (m Model) Close() { m.unsubscribe() }
+
 func (m Model) Init() tea.Cmd {
 	// UPDATED: Return ClearScreen to help with artifacting on startup
-	return tea.Batch(tea.ClearScreen, tea.Tick(time.Second, func(t time.Time) tea.Msg { return t }))
+	return tea.Batch(tea.ClearScreen, tea.Tick(time.Second, func(t time.Time) tea.Msg { return t }), waitForEvent(m.events))
 }
 
-func (m *Model) nextFocus(dir int) int {
-	next := m.focus + dir
-	if next > 7 { next = 0 }
-	if next < 0 { next = 7 }
-	isPush := m.siteInputs[1].Value() == "push"
-	
-	if isPush {
-		if next == 2 { if dir > 0 { next = 3 } else { next = 1 } }
-		if next == 5 || next == 6 { if dir > 0 { next = 7 } else { next = 4 } }
+// waitForEvent delivers the next store change as a message; nil once unsubscribed.
+func waitForEvent(events <-chan store.Event) tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-events
+		if !ok { return nil }
+		return e
 	}
-	return next
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -125,6 +156,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.logViewport.Width = msg.Width; m.logViewport.Height = msg.Height - 6
 		m.formViewport.Width = msg.Width; m.formViewport.Height = msg.Height - 6
 		m.alertList.SetSize(msg.Width, msg.Height-4)
+		m.stepsEditor.SetWidth(msg.Width - 4); m.stepsEditor.SetHeight(msg.Height - 10)
 		// Force a clear on resize (helps with attach sometimes)
 		return m, tea.ClearScreen
 
@@ -132,6 +164,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.refreshData()
 		return m, tea.Tick(time.Second, func(t time.Time) tea.Msg { return t })
 
+	case checkDoneMsg:
+		if msg.id == m.detailID { m.checking = false; m.checkResult = &msg }
+		m.refreshData()
+		return m, nil
+
+	case store.Event:
+		// Another session, the API or the database changed the config
+		m.refreshData()
+		return m, waitForEvent(m.events)
+
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" { return m, tea.Quit }
 		
@@ -157,13 +199,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.formViewport.GotoTop()
 						m.updateFormContent()
 						return m, nil
-					} else { m.siteInputs[4].SetValue(strconv.Itoa(itm.id)); m.state = stateFormSite; m.updateFormContent(); return m, nil }
+					} else { m.siteInputs[fieldAlert].SetValue(strconv.Itoa(itm.id)); m.state = stateFormSite; m.updateFormContent(); return m, nil }
 				}
 			}
 			m.alertList, cmd = m.alertList.Update(msg); return m, cmd
 		}
 
+		if m.state == stateEditSteps {
+			switch msg.String() {
+			case "esc": m.stepsEditor.Blur(); m.errorMsg = ""; m.state = stateFormSite; m.updateFormContent(); return m, nil
+			case "ctrl+s": if m.saveStepsEditor() { m.updateFormContent() }; return m, nil
+			}
+			m.stepsEditor, cmd = m.stepsEditor.Update(msg); return m, cmd
+		}
+
+		if m.filtering {
+			switch msg.String() {
+			case "esc": m.filtering = false; m.filterInput.Blur()
+			case "enter":
+				m.filtering = false; m.filterInput.Blur()
+				m.tagFilter = store.SplitTags(m.filterInput.Value()); m.cursor = 0; m.tableOffset = 0; m.refreshData()
+			default: m.filterInput, cmd = m.filterInput.Update(msg); return m, cmd
+			}
+			return m, nil
+		}
+
 		switch m.state {
+		case stateSiteDetail:
+			switch msg.String() {
+			case "esc", "q": m.state = stateDashboard; m.confirmRotate = false
+			case "c": if !m.checking { return m, m.startCheck() }
+			case "r": m.rotateToken()
+			}
+			return m, nil
+
 		case stateDashboard, stateLogs, stateUsers:
 			switch msg.String() {
 			case "q": return m, tea.Quit
@@ -175,6 +244,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.currentTab == 2 { m.state = stateLogs } else if m.currentTab == 3 { m.state = stateUsers } else { m.state = stateDashboard }
 			case "pgup", "pgdown":
 				if m.state == stateLogs { m.logViewport, cmd = m.logViewport.Update(msg); return m, cmd }
+			case "/":
+				if m.currentTab == 0 {
+					m.filtering = true; m.filterInput.SetValue(strings.Join(m.tagFilter, " ")); m.filterInput.CursorEnd()
+					return m, m.filterInput.Focus()
+				}
+			case "esc":
+				if m.currentTab == 0 && len(m.tagFilter) > 0 { m.tagFilter = nil; m.cursor = 0; m.tableOffset = 0; m.refreshData() }
 			case "up", "k":
 				if m.state == stateLogs { m.logViewport.LineUp(1) } else if m.cursor > 0 {
 					m.cursor--; if m.cursor < m.tableOffset { m.tableOffset = m.cursor }
@@ -210,26 +286,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.alertInputs[4].SetValue(target.Settings["user"]); m.alertInputs[5].SetValue(target.Settings["pass"])
 						m.alertInputs[6].SetValue(target.Settings["from"]); m.alertInputs[7].SetValue(target.Settings["to"])
 					} else { m.alertInputs[2].SetValue(target.Settings["url"]) }
+					m.alertInputs[len(m.alertInputs)-1].SetValue(target.Settings["group_window"])
 					
 					m.formViewport.SetYOffset(0); m.formViewport.GotoTop(); m.updateFormContent(); return m, nil
 				} else if m.currentTab == 0 && len(m.sites) > 0 {
 					target := m.sites[m.cursor]; m.editID = target.ID; m.editToken = target.Token; m.state = stateFormSite; m.initFormSite()
-					m.siteInputs[0].SetValue(target.Name)
-					m.siteInputs[1].SetValue(target.Type)
-					m.siteInputs[2].SetValue(target.URL)
-					m.siteInputs[3].SetValue(strconv.Itoa(target.Interval))
-					m.siteInputs[4].SetValue(strconv.Itoa(target.AlertID))
-					sslVal := "n"; if target.CheckSSL { sslVal = "y" }; m.siteInputs[5].SetValue(sslVal)
-					m.siteInputs[6].SetValue(strconv.Itoa(target.ExpiryThreshold)); m.siteInputs[7].SetValue(strconv.Itoa(target.MaxRetries))
+					m.loadSiteForm(target)
 					
 					m.formViewport.SetYOffset(0); m.formViewport.GotoTop(); m.updateFormContent(); return m, nil
 				}
 
+			case "i", "c":
+				if m.currentTab == 0 && len(m.sites) > 0 {
+					m.state = stateSiteDetail; m.detailID = m.sites[m.cursor].ID; m.checkResult = nil; m.checking = false
+					m.confirmRotate = false; m.detailNotice = ""
+					if msg.String() == "c" { return m, m.startCheck() }
+				}
+
 			case "d", "backspace":
 				if m.currentTab == 1 && len(m.alerts) > 0 {
 					store.Get().DeleteAlert(m.alerts[m.cursor].ID); m.adjustCursor(len(m.alerts)-1)
 				} else if m.currentTab == 0 && len(m.sites) > 0 {
-					id := m.sites[m.cursor].ID; store.Get().DeleteSite(id); monitor.RemoveSite(id); m.adjustCursor(len(m.sites)-1)
+					store.Get().DeleteSite(m.sites[m.cursor].ID); m.adjustCursor(len(m.sites)-1)
 				} else if m.currentTab == 3 && m.isAdmin && len(m.users) > 0 {
 					store.Get().DeleteUser(m.users[m.cursor].ID); m.adjustCursor(len(m.users)-1)
 				}
@@ -255,14 +333,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.switchAlertType(types[currIdx]); m.updateFormContent(); return m, nil
 				}
 				if m.state == stateFormSite && m.focus == 1 {
-					if m.siteInputs[1].Value() == "http" { m.siteInputs[1].SetValue("push") } else { m.siteInputs[1].SetValue("http") }
+					if msg.String() == "right" { m.cycleSiteType(1) } else { m.cycleSiteType(-1) }
 					m.updateFormContent(); return m, nil
 				}
 			case "tab", "shift+tab", "enter", "up", "down":
 				s := msg.String()
-				if m.state == stateFormSite && m.focus == 4 && s == "enter" { m.openAlertSelector(); return m, nil }
+				if m.state == stateFormSite && m.focus == fieldAlert && s == "enter" { m.openAlertSelector(); return m, nil }
+				if m.state == stateFormSite && m.focus == fieldSteps && s == "enter" { m.openStepsEditor(); return m, textarea.Blink }
 				
-				if s == "enter" && m.focus == len(currentInputs)-1 {
+				lastField := len(currentInputs) - 1
+				if m.state == stateFormSite { lastField = m.lastSiteField() }
+				if s == "enter" && m.focus == lastField {
 					if m.validateForm() { m.submitForm(); m.refreshData() } else { m.updateFormContent() }
 					return m, nil
 				}
@@ -280,6 +361,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.formViewport.SetYOffset(m.focus * 4); m.updateFormContent()
 				return m, tea.Batch(cmds...)
 			default:
+				if m.state == stateFormSite && m.focus == fieldSteps { return m, nil } // Edited in the steps editor only
 				if m.state == stateFormSite { for i := range m.siteInputs { m.siteInputs[i], cmd = m.siteInputs[i].Update(msg); cmds = append(cmds, cmd) }
 				} else if m.state == stateFormAlert { for i := range m.alertInputs { m.alertInputs[i], cmd = m.alertInputs[i].Update(msg); cmds = append(cmds, cmd) }
 				} else if m.state == stateFormUser { for i := range m.userInputs { m.userInputs[i], cmd = m.userInputs[i].Update(msg); cmds = append(cmds, cmd) } }
@@ -290,6 +372,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// startCheck runs an on-demand check of the detail site in the background.
+func (m *Model) startCheck() tea.Cmd {
+	m.checking = true; m.checkResult = nil
+	id := m.detailID
+	return func() tea.Msg {
+		res, err := monitor.CheckNow(id)
+		return checkDoneMsg{id: id, res: res, err: err}
+	}
+}
+
+// rotateToken issues a new push token for the detail site after a second [r] press.
+func (m *Model) rotateToken() {
+	var site models.Site
+	for _, s := range m.sites { if s.ID == m.detailID { site = s } }
+	if site.Type != "push" { return }
+	if !m.confirmRotate {
+		m.confirmRotate = true; m.detailNotice = "Press [r] again to rotate. The current push URL stops working immediately."
+		return
+	}
+	m.confirmRotate = false
+	if _, ok := store.Get().RotateToken(site.ID); ok { m.detailNotice = "Token rotated. Update your jobs with the new push URL." } else { m.detailNotice = "Token rotation failed." }
+}
+
 func (m *Model) adjustCursor(newLen int) {
 	if m.cursor >= newLen && m.cursor > 0 { m.cursor-- }
 	if m.cursor < m.tableOffset { m.tableOffset = m.cursor; if m.tableOffset < 0 { m.tableOffset = 0 } }
@@ -308,7 +413,10 @@ func (m *Model) focusInputs() []tea.Cmd {
 
 func (m *Model) refreshData() {
 	monitor.Mutex.RLock(); var sites []models.Site; for _, s := range monitor.LiveState { sites = append(sites, s) }; monitor.Mutex.RUnlock()
-	sort.Slice(sites, func(i, j int) bool { return sites[i].ID < sites[j].ID }); m.sites = sites
+	m.siteTotal = len(sites)
+	if len(m.tagFilter) > 0 { sites = slices.DeleteFunc(sites, func(s models.Site) bool { return !s.MatchesTags(m.tagFilter) }) }
+	sort.Slice(sites, func(i, j int) bool { return sites[i].ID < sites[j].ID }); m.sites, m.siteDepth, m.siteAlso = dependencyTree(sites)
+	if m.currentTab == 0 && m.cursor >= len(m.sites) { m.cursor = max(len(m.sites)-1, 0); m.tableOffset = min(m.tableOffset, m.cursor) }
 	if store.Get() != nil { 
 		m.alerts = store.Get().GetAllAlerts() 
 		if m.isAdmin { m.users = store.Get().GetAllUsers() }
@@ -316,19 +424,6 @@ func (m *Model) refreshData() {
 	m.logViewport.SetContent(strings.Join(monitor.GetLogs(), "\n"))
 }
 
-func (m *Model) initFormSite() {
-	m.siteInputs = make([]textinput.Model, 8) 
-	m.siteInputs[0] = ti("My Monitor", 30); m.siteInputs[0].Focus()
-	m.siteInputs[1] = ti("http", 10); m.siteInputs[1].SetValue("http") 
-	m.siteInputs[2] = ti("https://example.com", 30)
-	m.siteInputs[3] = ti("60", 10)
-	m.siteInputs[4] = ti("", 20)
-	m.siteInputs[5] = ti("n", 5)
-	m.siteInputs[6] = ti("7", 5)
-	m.siteInputs[7] = ti("0", 5)
-	m.focus = 0; m.errorMsg = ""
-}
-
 func (m *Model) initFormAlert() {
 	m.currentAlertType = "discord"; m.switchAlertType("discord")
 	m.focus = 0; m.errorMsg = ""
@@ -347,7 +442,7 @@ func (m *Model) switchAlertType(t string) {
 	nameVal := ""; if len(m.alertInputs) > 0 { nameVal = m.alertInputs[0].Value() }
 	
 	if t == "email" {
-		m.alertInputs = make([]textinput.Model, 8)
+		m.alertInputs = make([]textinput.Model, 9)
 		m.alertInputs[0] = ti("Alert Name", 20); m.alertInputs[0].SetValue(nameVal)
 		m.alertInputs[1] = ti(t, 20); m.alertInputs[1].SetValue(t)
 		m.alertInputs[2] = ti("smtp.gmail.com", 30)
@@ -357,11 +452,12 @@ func (m *Model) switchAlertType(t string) {
 		m.alertInputs[6] = ti("from@domain.com", 30)
 		m.alertInputs[7] = ti("to@domain.com", 30)
 	} else {
-		m.alertInputs = make([]textinput.Model, 3)
+		m.alertInputs = make([]textinput.Model, 4)
 		m.alertInputs[0] = ti("Alert Name", 20); m.alertInputs[0].SetValue(nameVal)
 		m.alertInputs[1] = ti(t, 20); m.alertInputs[1].SetValue(t)
 		m.alertInputs[2] = ti("Webhook URL", 50)
 	}
+	m.alertInputs[len(m.alertInputs)-1] = ti("0", 10)
 	if m.focus >= len(m.alertInputs) { m.focus = len(m.alertInputs) - 1 }
 	m.alertInputs[m.focus].Focus()
 }
@@ -384,43 +480,7 @@ func (m *Model) updateFormContent() {
 	if m.errorMsg != "" { content += dangerStyle.Render("Error: "+m.errorMsg) + "\n\n" }
 
 	if m.state == stateFormSite {
-		title := "Add Monitor"; if m.editID > 0 { title = fmt.Sprintf("Edit Monitor #%d", m.editID) }
-		content += titleStyle.Render(title) + "\n\n"
-		
-		content += "Name:\n" + m.siteInputs[0].View() + "\n\n"
-		
-		lbl := "Type (< Left / Right >):"
-		val := strings.ToUpper(m.siteInputs[1].Value())
-		if m.focus == 1 { lbl = specialStyle.Render(lbl); val = specialStyle.Render(val) }
-		content += lbl + "\n" + val + "\n\n"
-		
-		isPush := m.siteInputs[1].Value() == "push"
-
-		if !isPush {
-			content += "URL:\n" + m.siteInputs[2].View() + "\n\n"
-		} else {
-			if m.editToken != "" {
-				content += "Push URL (Secret!):\n" + subtleStyle.Render(fmt.Sprintf("GET /api/push?token=%s", m.editToken)) + "\n\n"
-			} else {
-				content += "Push URL:\n" + subtleStyle.Render("(Generated securely after saving)") + "\n\n"
-			}
-		}
-
-		content += "Interval / Heartbeat (sec):\n" + m.siteInputs[3].View() + "\n\n"
-		
-		lbl = "Alert ID:"; val = m.siteInputs[4].Value()
-		if val == "" || val == "0" { val = "[Enter to Select]" } else { val = fmt.Sprintf("(ID: %s) [Enter to Change]", val) }
-		if m.focus == 4 { lbl = specialStyle.Render(lbl); val = specialStyle.Render(val) }
-		content += lbl + "\n" + val + "\n\n"
-		
-		if !isPush {
-			content += "Check SSL? (y/n):\n" + m.siteInputs[5].View() + "\n\n"
-			content += "SSL Warning Threshold (days):\n" + m.siteInputs[6].View() + "\n\n"
-		} else {
-			content += subtleStyle.Render("SSL Checks disabled for Push monitors.") + "\n\n"
-		}
-		
-		content += "Max Retries / Tolerance:\n" + m.siteInputs[7].View() + "\n\n"
+		content += m.viewSiteForm()
 
 	} else if m.state == stateFormAlert {
 		title := "Add Alert"; if m.editID > 0 { title = fmt.Sprintf("Edit Alert #%d", m.editID) }
@@ -430,13 +490,16 @@ func (m *Model) updateFormContent() {
 		if m.focus == 1 { lbl = specialStyle.Render(lbl); val = specialStyle.Render(val) }
 		content += lbl + "\n" + val + "\n\n"
 		
-		if m.currentAlertType == "email" && len(m.alertInputs) >= 8 {
+		if m.currentAlertType == "email" && len(m.alertInputs) >= 9 {
 			content += "SMTP Host:\n" + m.alertInputs[2].View() + "\n\n" + "Port:\n" + m.alertInputs[3].View() + "\n\n" +
 				"User:\n" + m.alertInputs[4].View() + "\n\n" + "Pass:\n" + m.alertInputs[5].View() + "\n\n" +
 				"From Email:\n" + m.alertInputs[6].View() + "\n\n" + "To Email:\n" + m.alertInputs[7].View() + "\n\n"
-		} else if len(m.alertInputs) >= 3 {
+		} else if len(m.alertInputs) >= 4 {
 			content += "Webhook URL:\n" + m.alertInputs[2].View() + "\n\n"
 		}
+		if len(m.alertInputs) > 0 {
+			content += "Group Window (sec, 0 = send immediately):\n" + m.alertInputs[len(m.alertInputs)-1].View() + "\n\n"
+		}
 	} else if m.state == stateFormUser {
 		title := "Add User (SSH Access)"
 		content += titleStyle.Render(title) + "\n\n"
@@ -448,8 +511,20 @@ func (m *Model) updateFormContent() {
 
 func (m *Model) validateForm() bool {
 	if m.state == stateFormSite { 
-		if m.siteInputs[0].Value() == "" { m.errorMsg = "Name is required"; return false }
-		if m.siteInputs[1].Value() == "http" && m.siteInputs[2].Value() == "" { m.errorMsg = "URL is required"; return false }
+		if m.siteInputs[fieldName].Value() == "" { m.errorMsg = "Name is required"; return false }
+		if siteFieldVisible(fieldURL, m.siteType()) && m.siteInputs[fieldURL].Value() == "" { m.errorMsg = "URL is required"; return false }
+		if m.errorMsg = validateSiteFields(m.siteInputs, m.siteType()); m.errorMsg != "" { return false }
+		if m.editID > 0 {
+			sites := store.Get().GetSites()
+			for _, pid := range store.SplitIDs(m.siteInputs[fieldParents].Value()) {
+				if pid != m.editID && models.DependsOn(sites, pid, m.editID) { m.errorMsg = fmt.Sprintf("Monitor %d already depends on this one", pid); return false }
+			}
+			if m.siteType() == "group" {
+				for _, mb := range store.SplitMembers(m.siteInputs[fieldMembers].Value()) {
+					if mb.ID != m.editID && models.GroupContains(sites, mb.ID, m.editID) { m.errorMsg = fmt.Sprintf("Group %d already contains this one", mb.ID); return false }
+				}
+			}
+		}
 	}
 	if m.state == stateFormAlert {
 		if m.alertInputs[0].Value() == "" { m.errorMsg = "Name is required"; return false }
@@ -465,21 +540,8 @@ func (m *Model) validateForm() bool {
 
 func (m *Model) submitForm() {
 	if m.state == stateFormSite {
-		name := m.siteInputs[0].Value()
-		sType := m.siteInputs[1].Value()
-		url := m.siteInputs[2].Value()
-		interval, _ := strconv.Atoi(m.siteInputs[3].Value())
-		alertID, _ := strconv.Atoi(m.siteInputs[4].Value())
-		checkSSL := false; if strings.ToLower(m.siteInputs[5].Value()) == "y" { checkSSL = true }
-		threshold, _ := strconv.Atoi(m.siteInputs[6].Value())
-		retries, _ := strconv.Atoi(m.siteInputs[7].Value())
-		if interval < 1 { interval = 60 }
-		if threshold < 1 { threshold = 7 }
-
-		if m.editID > 0 {
-			store.Get().UpdateSite(m.editID, name, url, sType, interval, alertID, checkSSL, threshold, retries)
-			monitor.UpdateSiteConfig(m.editID, name, url, sType, interval, alertID, checkSSL, threshold, retries)
-		} else { store.Get().AddSite(name, url, sType, interval, alertID, checkSSL, threshold, retries) }
+		site := m.siteFromForm()
+		if m.editID > 0 { store.Get().UpdateSite(site) } else { store.Get().AddSite(site) }
 		m.state = stateDashboard
 
 	} else if m.state == stateFormAlert {
@@ -491,6 +553,7 @@ func (m *Model) submitForm() {
 			settings["user"] = m.alertInputs[4].Value(); settings["pass"] = m.alertInputs[5].Value()
 			settings["from"] = m.alertInputs[6].Value(); settings["to"] = m.alertInputs[7].Value()
 		} else { settings["url"] = m.alertInputs[2].Value() }
+		if gw := m.alertInputs[len(m.alertInputs)-1].Value(); gw != "" { settings["group_window"] = gw }
 
 		if m.editID > 0 { store.Get().UpdateAlert(m.editID, name, atype, settings) } else { store.Get().AddAlert(name, atype, settings) }
 
@@ -498,7 +561,7 @@ func (m *Model) submitForm() {
 			m.creatingAlertFromSite = false; alerts := store.Get().GetAllAlerts()
 			if len(alerts) > 0 {
 				last := alerts[len(alerts)-1]; m.state = stateFormSite
-				m.siteInputs[4].SetValue(strconv.Itoa(last.ID)); m.focus = 4; m.updateFormContent()
+				m.siteInputs[fieldAlert].SetValue(strconv.Itoa(last.ID)); m.focus = fieldAlert; m.updateFormContent()
 			}
 		} else { m.state = stateDashboard }
 	} else if m.state == stateFormUser {
@@ -516,6 +579,10 @@ func (m Model) View() string {
 	case stateFormSite, stateFormAlert, stateFormUser:
 		f := subtleStyle.Render("\n[Enter] Save  [PgUp/PgDn] Scroll  [Esc] Cancel")
 		return m.formViewport.View() + "\n" + f
+	case stateSiteDetail:
+		return m.viewSiteDetail()
+	case stateEditSteps:
+		return m.viewStepsEditor()
 	default:
 		return m.viewDashboard()
 	}
@@ -532,17 +599,24 @@ func (m Model) viewDashboard() string {
 	content := ""
 
 	if m.currentTab == 0 {
-		headerStr := lipgloss.JoinHorizontal(lipgloss.Left, colID.Render("ID"), colName.Render("NAME"), colType.Render("TYPE"), colURL.Render("URL/DESC"), colStatus.Render("STATUS"), colSSL.Render("SSL CERT"), colRetries.Render("RETRY"))
+		if m.filtering {
+			content += "\n" + m.filterInput.View() + "\n"
+		} else if len(m.tagFilter) > 0 {
+			content += "\n" + warnStyle.Render(fmt.Sprintf("Tags: %s (%d of %d)", strings.Join(m.tagFilter, " "), len(m.sites), m.siteTotal)) + subtleStyle.Render("  [/] Change  [Esc] Clear") + "\n"
+		}
+		headerStr := lipgloss.JoinHorizontal(lipgloss.Left, colID.Render("ID"), colName.Render("NAME"), colType.Render("TYPE"), colURL.Render("URL/DESC"), colStatus.Render("STATUS"), colSSL.Render("EXPIRY"), colRetries.Render("RETRY"))
 		content += "\n" + headerStr + "\n" + subtleStyle.Render(strings.Repeat("-", 100)) + "\n"
 		end := m.tableOffset + m.maxTableRows; if end > len(m.sites) { end = len(m.sites) }
-		if len(m.sites) == 0 { content += "\n  No sites configured." } else {
+		if len(m.sites) == 0 && m.siteTotal > 0 { content += "\n  No sites match the filter." } else if len(m.sites) == 0 { content += "\n  No sites configured." } else {
 			for i := m.tableOffset; i < end; i++ {
 				site := m.sites[i]; cursor := " "; if m.cursor == i { cursor = ">" }
 				statusStyle := specialStyle
-				if site.Status == "DOWN" || site.Status == "SSL EXP" { statusStyle = dangerStyle } else if site.Status == "PENDING" { statusStyle = subtleStyle }
+				if site.Status == "DOWN" || site.Status == "SSL EXP" { statusStyle = dangerStyle } else if site.Status == "PENDING" { statusStyle = subtleStyle } else if site.Status == "UNREACHABLE" { statusStyle = mutedStyle } else if site.Status == "FLAP" || site.Status == "DEGRADED" { statusStyle = warnStyle }
 				sslStr := "-"
-				if site.Type == "http" && site.CheckSSL && site.HasSSL {
-					days := int(time.Until(site.CertExpiry).Hours() / 24); s := fmt.Sprintf("%d days", days)
+				expiry := site.CertExpiry
+				if site.Type == "domain" { expiry = site.DomainExpiry }
+				if (site.CheckSSL && site.HasSSL) || (site.Type == "domain" && !expiry.IsZero()) {
+					days := int(time.Until(expiry).Hours() / 24); s := fmt.Sprintf("%d days", days)
 					if days <= 0 { sslStr = dangerStyle.Render("EXPIRED") } else if days <= site.ExpiryThreshold { sslStr = warnStyle.Render(s) } else { sslStr = specialStyle.Render(s) }
 				}
 				retriesDone := site.FailureCount - 1; if retriesDone < 0 { retriesDone = 0 }
@@ -550,10 +624,13 @@ func (m Model) viewDashboard() string {
 				retryStr := fmt.Sprintf("%d/%d", dispCount, site.MaxRetries)
 				if site.Status == "UP" && site.FailureCount > 0 { retryStr = warnStyle.Render(retryStr) }
 				if site.Status == "DOWN" { retryStr = dangerStyle.Render(retryStr) }
-				urlDisplay := site.URL
+				urlDisplay := site.Target()
 				if site.Type == "push" { urlDisplay = "(Passive Monitor)" }
-				row := lipgloss.JoinHorizontal(lipgloss.Left, colID.Render(strconv.Itoa(site.ID)), colName.Render(limitStr(site.Name, 14)), colType.Render(site.Type), colURL.Render(limitStr(urlDisplay, 24)), colStatus.Render(statusStyle.Render(site.Status)), colSSL.Render(sslStr), colRetries.Render(retryStr))
+				name := site.Name
+				if d := m.siteDepth[i]; d > 0 { name = strings.Repeat(" ", d-1) + "└" + name }
+				row := lipgloss.JoinHorizontal(lipgloss.Left, colID.Render(strconv.Itoa(site.ID)), colName.Render(limitStr(name, 14)), colType.Render(site.Type), colURL.Render(limitStr(urlDisplay, 24)), colStatus.Render(statusStyle.Render(site.Status)), colSSL.Render(sslStr), colRetries.Render(retryStr))
 				if m.cursor == i { row = lipgloss.NewStyle().Bold(true).Render(cursor + row) } else { row = " " + row }
+				if ids := m.siteAlso[site.ID]; len(ids) > 0 { row += subtleStyle.Render(" also under #" + strings.ReplaceAll(store.JoinIDs(ids), ",", ", #")) }
 				content += row + "\n"
 			}
 		}
@@ -585,11 +662,136 @@ func (m Model) viewDashboard() string {
 	}
 	
 	footer := subtleStyle.Render("\n[n] New  [e/Enter] Edit  [d] Delete  [Tab] Switch View  [Ctrl+L] Clear Screen  [q] Quit")
+	if m.currentTab == 0 { footer = subtleStyle.Render("\n[n] New  [e/Enter] Edit  [i] Info  [c] Check Now  [d] Delete  [/] Filter  [Tab] Switch View  [q] Quit") }
 	if m.currentTab == 3 { footer = subtleStyle.Render("\n[n] Add User  [d] Revoke Access  [Tab] Switch View  [Ctrl+L] Clear Screen  [q] Quit") }
 	return lipgloss.NewStyle().Padding(1, 2).Render(header + "\n" + content + "\n" + footer)
 }
 
+func (m Model) viewSiteDetail() string {
+	var site models.Site; found := false
+	for _, s := range m.sites { if s.ID == m.detailID { site = s; found = true; break } }
+	if !found { return lipgloss.NewStyle().Padding(1, 2).Render("Monitor no longer exists.\n\n" + subtleStyle.Render("[Esc] Back")) }
+
+	content := titleStyle.Render(fmt.Sprintf("Monitor #%d - %s", site.ID, site.Name)) + "\n\n"
+	content += fmt.Sprintf("Type:        %s\n", site.Type)
+	if len(site.Tags) > 0 { content += fmt.Sprintf("Tags:        %s\n", store.JoinTags(site.Tags)) }
+	if site.Type == "ping" || site.Type == "domain" {
+		content += fmt.Sprintf("Host:        %s\n", site.URL)
+	} else if site.Type != "push" { content += fmt.Sprintf("Target:      %s\n", site.Target()) }
+	if site.Type == "group" {
+		for _, mb := range site.Members {
+			name, status := "(deleted)", ""
+			for _, s := range m.sites { if s.ID == mb.ID { name, status = s.Name, s.Status } }
+			weight := ""; if site.Rule() == "weighted" { weight = fmt.Sprintf(" ×%d", max(mb.Weight, 1)) }
+			content += fmt.Sprintf("  #%-4d %-20s %s%s\n", mb.ID, limitStr(name, 20), status, weight)
+		}
+		if site.MuteMembers { content += "Member alerts muted\n" }
+	}
+	if site.Type == "grpc" && site.Service != "" { content += fmt.Sprintf("Service:     %s\n", site.Service) }
+	if site.Type == "docker" && site.ContainerState != "" { content += fmt.Sprintf("Container:   %s, %d restarts\n", site.ContainerState, site.RestartCount) }
+	if site.Banner != "" && (site.Type == "ssh" || site.Type == "smtp") { content += fmt.Sprintf("Banner:      %s\n", site.Banner) }
+	if (site.Type == "websocket" || site.Type == "udp") && site.Payload != "" { content += fmt.Sprintf("Sends:       %s\n", site.Payload) }
+	if site.Expect != "" { content += fmt.Sprintf("Expect:      %s\n", site.Expect) }
+	content += fmt.Sprintf("Status:      %s\n", site.Status)
+	content += fmt.Sprintf("Code:        %d\n", site.StatusCode)
+	content += fmt.Sprintf("Latency:     %dms\n", site.Latency.Milliseconds())
+	if site.Type == "ping" && !site.LastCheck.IsZero() {
+		content += fmt.Sprintf("Packet Loss: %.0f%%\n", site.PacketLoss)
+		content += fmt.Sprintf("RTT:         min %s / avg %s / max %s\n", fmtRTT(site.RTTMin), fmtRTT(site.RTTAvg), fmtRTT(site.RTTMax))
+	}
+	if site.Type != "push" && site.LastError != "" { content += fmt.Sprintf("Last Error:  %s\n", dangerStyle.Render(site.LastError)) }
+	if site.Type == "domain" && !site.DomainExpiry.IsZero() {
+		days := int(time.Until(site.DomainExpiry).Hours() / 24)
+		content += fmt.Sprintf("Expires:     %s (%d days)\n", site.DomainExpiry.Local().Format("2006-01-02"), days)
+		if site.Registrar != "" { content += fmt.Sprintf("Registrar:   %s\n", site.Registrar) }
+	}
+	if !site.LastCheck.IsZero() { content += fmt.Sprintf("Last Check:  %s\n", site.LastCheck.Format("2006-01-02 15:04:05")) }
+	if site.Type == "push" && !site.LastHeartbeat.IsZero() { content += fmt.Sprintf("Heartbeat:   %s\n", site.LastHeartbeat.Format("2006-01-02 15:04:05")) }
+	if site.Type == "push" {
+		content += fmt.Sprintf("Push URL:    %s\n", monitor.PushURL(site.Token))
+		if site.Cron != "" { content += fmt.Sprintf("Schedule:    %s %s\n", site.Cron, site.Timezone) }
+		if !site.JobStarted.IsZero() { content += fmt.Sprintf("Running:     since %s\n", site.JobStarted.Format("2006-01-02 15:04:05")) }
+		if due, err := monitor.PushDeadline(site); err == nil { content += fmt.Sprintf("Due By:      %s\n", due.Local().Format("2006-01-02 15:04:05")) }
+	}
+	if site.Type == "push" && site.LastMessage != "" {
+		msgStyle := subtleStyle; if site.PushFailed { msgStyle = dangerStyle }
+		content += fmt.Sprintf("Message:     %s\n", msgStyle.Render(site.LastMessage))
+	}
+	for i, st := range site.Steps {
+		method := st.Method; if method == "" { method = "GET"; if st.Body != "" { method = "POST" } }
+		label := "Steps:"; if i > 0 { label = "" }
+		content += fmt.Sprintf("%-13s%d. %s %s %s\n", label, i+1, strings.ToUpper(method), st.URL, subtleStyle.Render(st.Name))
+	}
+	if len(site.ParentIDs) > 0 { content += fmt.Sprintf("Depends On:  %s\n", store.JoinIDs(site.ParentIDs)) }
+	if auth := authSummary(site); auth != "" { content += fmt.Sprintf("Auth:        %s\n", auth) }
+
+	content += "\n" + titleStyle.Render("Manual Check") + "\n\n"
+	if m.checking {
+		content += warnStyle.Render("Checking...") + "\n"
+	} else if m.checkResult == nil {
+		content += subtleStyle.Render("Press [c] to check now.") + "\n"
+	} else if m.checkResult.err != nil {
+		content += dangerStyle.Render("Error: "+m.checkResult.err.Error()) + "\n"
+	} else {
+		r := m.checkResult.res
+		statusStyle := specialStyle; if r.Status != "UP" { statusStyle = dangerStyle }
+		content += fmt.Sprintf("Status:      %s\n", statusStyle.Render(r.Status))
+		content += fmt.Sprintf("Code:        %d\n", r.StatusCode)
+		content += fmt.Sprintf("Latency:     %dms\n", r.Latency.Milliseconds())
+		if r.Error != "" { content += fmt.Sprintf("Error:       %s\n", dangerStyle.Render(r.Error)) }
+		content += fmt.Sprintf("Checked At:  %s\n", r.CheckedAt.Format("15:04:05"))
+	}
+
+	if m.detailNotice != "" { content += "\n" + warnStyle.Render(m.detailNotice) + "\n" }
+	keys := "[c] Check Now  [Esc] Back"
+	if site.Type == "push" { keys = "[c] Check Now  [r] Rotate Token  [Esc] Back" }
+	footer := subtleStyle.Render("\n" + keys)
+	return lipgloss.NewStyle().Padding(1, 2).Render(content + footer)
+}
+
+func fmtRTT(d time.Duration) string { return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond)) }
+
 func limitStr(text string, max int) string {
 	if len(text) > max { return text[:max-3] + "..." }
 	return text
-}
\ No newline at end of file
+}
+
+// dependencyTree orders sites so each appears beneath its first parent, returning
+// the rows, their depth and, per site, the other parents it is not listed under.
+func dependencyTree(sites []models.Site) ([]models.Site, []int, map[int][]int) {
+	byID := make(map[int]bool)
+	for _, s := range sites { byID[s.ID] = true }
+	// Group members nest under their groups the same way dependents nest under parents
+	groupsOf := make(map[int][]int)
+	for _, s := range sites {
+		if s.Type != "group" { continue }
+		for _, mb := range s.Members {
+			if byID[mb.ID] && mb.ID != s.ID && !slices.Contains(groupsOf[mb.ID], s.ID) { groupsOf[mb.ID] = append(groupsOf[mb.ID], s.ID) }
+		}
+	}
+	children := make(map[int][]models.Site)
+	also := make(map[int][]int)
+	var roots []models.Site
+	for _, s := range sites {
+		var parents []int
+		for _, pid := range s.ParentIDs { if byID[pid] && pid != s.ID && !slices.Contains(parents, pid) { parents = append(parents, pid) } }
+		for _, gid := range groupsOf[s.ID] { if !slices.Contains(parents, gid) { parents = append(parents, gid) } }
+		if len(parents) == 0 { roots = append(roots, s); continue }
+		children[parents[0]] = append(children[parents[0]], s)
+		if len(parents) > 1 { also[s.ID] = parents[1:] }
+	}
+
+	var rows []models.Site; var depths []int
+	seen := make(map[int]bool)
+	var walk func(s models.Site, depth int)
+	walk = func(s models.Site, depth int) {
+		seen[s.ID] = true
+		rows = append(rows, s); depths = append(depths, depth)
+		for _, c := range children[s.ID] { if !seen[c.ID] { walk(c, depth+1) } }
+	}
+	for _, r := range roots { walk(r, 0) }
+
+	// Sites caught in a dependency cycle have no root; list them flat so they stay visible
+	for _, s := range sites { if !seen[s.ID] { rows = append(rows, s); depths = append(depths, 0) } }
+	return rows, depths, also
+}
@@ -0,0 +1,23 @@
+package tui
+
+import (
+	"go-upkeep/internal/models"
+	"slices"
+	"testing"
+)
+
+func TestDependencyTreeListsEachSiteOnce(t *testing.T) {
+	sites := []models.Site{
+		{ID: 1}, {ID: 2},
+		{ID: 3, ParentIDs: []int{1, 2}}, // Two parents
+		{ID: 4, ParentIDs: []int{3}},
+		{ID: 5, Type: "group", Members: []models.GroupMember{{ID: 4}}}, // 4 is also a group member
+		{ID: 6, ParentIDs: []int{7}}, {ID: 7, ParentIDs: []int{6}}, // Cycle: no root
+	}
+	rows, depths, also := dependencyTree(sites)
+	var ids []int
+	for _, r := range rows { ids = append(ids, r.ID) }
+	if want := []int{1, 3, 4, 2, 5, 6, 7}; !slices.Equal(ids, want) { t.Errorf("rows = %v, want %v", ids, want) }
+	if want := []int{0, 1, 2, 0, 0, 0, 0}; !slices.Equal(depths, want) { t.Errorf("depths = %v, want %v", depths, want) }
+	if !slices.Equal(also[3], []int{2}) || !slices.Equal(also[4], []int{5}) || len(also) != 2 { t.Errorf("also = %v", also) }
+}